@@ -65,6 +65,10 @@ public:
         : t_generator(program) {
         std::map<std::string, std::string>::const_iterator iter;
         out_dir_base_ = "gen-go";
+        iter = parsed_options.find("concurrent_client");
+        gen_concurrent_client_ = (iter != parsed_options.end());
+        iter = parsed_options.find("context");
+        gen_context_ = (iter != parsed_options.end());
     }
 
     /**
@@ -105,6 +109,7 @@ public:
     void generate_service_helpers   (t_service*  tservice);
     void generate_service_interface (t_service* tservice);
     void generate_service_client    (t_service* tservice);
+    void generate_service_concurrent_client(t_service* tservice);
     void generate_service_remote    (t_service* tservice);
     void generate_service_server    (t_service* tservice);
     void generate_process_function  (t_service* tservice, t_function* tfunction);
@@ -202,14 +207,16 @@ public:
 
     std::string go_autogen_comment();
     std::string go_package();
-    std::string go_imports();
+    std::string go_imports(bool importContext = false);
     std::string render_includes();
     std::string render_fastbinary_includes();
     std::string declare_argument(t_field* tfield);
     std::string render_field_default_value(t_field* tfield, const string& name);
     std::string type_name(t_type* ttype);
     std::string function_signature(t_function* tfunction, std::string prefix = "");
-    std::string function_signature_if(t_function* tfunction, std::string prefix = "", bool addOsError = false);
+    std::string function_signature_if(t_function* tfunction, std::string prefix = "", bool addOsError = false, bool addContext = false, std::string retval = "");
+    std::string function_results_if(t_function* tfunction, std::string retval, bool addOsError = false);
+    std::string function_result_names(t_function* tfunction, std::string retval, bool addOsError = false);
     std::string argument_list(t_struct* tstruct);
     std::string type_to_enum(t_type* ttype);
     std::string type_to_go_type(t_type* ttype);
@@ -239,6 +246,9 @@ private:
     std::string package_name_;
     std::string package_dir_;
 
+    bool gen_concurrent_client_;
+    bool gen_context_;
+
     static std::string publicize(const std::string& value);
     static std::string privatize(const std::string& value);
     static std::string variable_name_to_go_name(const std::string& value);
@@ -530,11 +540,12 @@ string t_go_generator::go_package()
 /**
  * Prints standard thrift imports
  */
-string t_go_generator::go_imports()
+string t_go_generator::go_imports(bool importContext)
 {
     return
-        string("import (\n"
-               "\t\"fmt\"\n"
+        string("import (\n") +
+        (importContext ? "\t\"context\"\n" : "") +
+        string("\t\"fmt\"\n"
                "\t\"math\"\n"
                "\t\"thrift\"\n"
                ")\n\n"
@@ -1406,7 +1417,8 @@ void t_go_generator::generate_service(t_service* tservice)
     f_service_ <<
                go_autogen_comment() <<
                go_package() <<
-               go_imports();
+               // only methods take a context
+               go_imports(gen_context_ && !tservice->get_functions().empty());
 
     if (tservice->get_extends() != NULL) {
         f_service_ <<
@@ -1512,7 +1524,7 @@ void t_go_generator::generate_service_interface(t_service* tservice)
         for (f_iter = functions.begin(); f_iter != functions.end(); ++f_iter) {
             generate_go_docstring(f_service_, (*f_iter));
             f_service_ <<
-                       indent() << function_signature_if(*f_iter, "", true) << endl;
+                       indent() << function_signature_if(*f_iter, "", true, gen_context_) << endl;
         }
     }
 
@@ -1522,12 +1534,19 @@ void t_go_generator::generate_service_interface(t_service* tservice)
 }
 
 /**
- * Generates a service client definition.
+ * Generates a service client definition.  Only concurrent clients get
+ * <Method>Async calls returning futures, as this client reads each reply
+ * before sending the next request and so cannot have calls in flight.
  *
  * @param tservice The service to generate a server for.
  */
 void t_go_generator::generate_service_client(t_service* tservice)
 {
+    if (gen_concurrent_client_) {
+        generate_service_concurrent_client(tservice);
+        return;
+    }
+
     string extends = "";
     string extends_client = "";
     string extends_client_new = "";
@@ -1560,7 +1579,9 @@ void t_go_generator::generate_service_client(t_service* tservice)
                    indent() << "ProtocolFactory thrift.TProtocolFactory" << endl <<
                    indent() << "InputProtocol thrift.TProtocol" << endl <<
                    indent() << "OutputProtocol thrift.TProtocol" << endl <<
-                   indent() << "SeqId int32" << endl /*<<
+                   indent() << "SeqId int32" << endl <<
+                   indent() << "Timeouts thrift.TClientTimeouts" << endl <<
+                   indent() << "Retries thrift.TRetryPolicy" << endl /*<<
       indent() << "reqs map[int32]Deferred" << endl*/;
     }
 
@@ -1629,9 +1650,11 @@ void t_go_generator::generate_service_client(t_service* tservice)
         vector<t_field*>::const_iterator fld_iter;
         string funname = publicize((*f_iter)->get_name());
         // Open function
+        string retval(tmp("retval"));
+        string signature = function_signature_if(*f_iter, "", true, gen_context_, retval);
         generate_go_docstring(f_service_, (*f_iter));
         f_service_ <<
-                   indent() << "func (p *" << serviceName << "Client) " << function_signature_if(*f_iter, "", true) << " {" << endl;
+                   indent() << "func (p *" << serviceName << "Client) " << signature << " {" << endl;
         indent_up();
         /*
         f_service_ <<
@@ -1642,8 +1665,30 @@ void t_go_generator::generate_service_client(t_service* tservice)
             indent() << "p.Reqs[p.SeqId] = d" << endl;
         }
         */
+        string escapedFuncName(escape_string((*f_iter)->get_name()));
+
+        if (gen_context_) {
+            f_service_ <<
+                       indent() << "retry := p.Retries.BeginContext(ctx, \"" << escapedFuncName << "\", p.Transport)" << endl;
+        } else {
+            f_service_ <<
+                       indent() << "retry := p.Retries.Begin(\"" << escapedFuncName << "\", p.Transport)" << endl;
+        }
+
+        f_service_ <<
+                   indent() << "for {" << endl;
+        indent_up();
+
+        if (gen_context_) {
+            f_service_ <<
+                       indent() << "done := thrift.WatchContextTimeout(ctx, p.Timeouts.Timeout(\"" << escapedFuncName << "\"), p.Transport)" << endl;
+        } else {
+            f_service_ <<
+                       indent() << "done := thrift.WatchTimeout(p.Timeouts.Timeout(\"" << escapedFuncName << "\"), p.Transport)" << endl;
+        }
+
         f_service_ <<
-                   indent() << "err = p.Send" << funname << "(";
+                   indent() << "if err = p.Send" << funname << "(";
         bool first = true;
 
         for (fld_iter = fields.begin(); fld_iter != fields.end(); ++fld_iter) {
@@ -1656,17 +1701,22 @@ void t_go_generator::generate_service_client(t_service* tservice)
             f_service_ << variable_name_to_go_name((*fld_iter)->get_name());
         }
 
-        f_service_ << ")" << endl <<
-                   indent() << "if err != nil { return }" << endl;
+        f_service_ << "); err == nil {" << endl;
 
         if (!(*f_iter)->is_oneway()) {
+            // assign the results of Recv to the named results of the method
             f_service_ <<
-                       indent() << "return p.Recv" << funname << "()" << endl;
-        } else {
-            f_service_ <<
-                       indent() << "return" << endl;
+                       indent() << "  " << function_result_names(*f_iter, retval, true) << " = p.Recv" << funname << "()" << endl;
         }
 
+        f_service_ <<
+                   indent() << "}" << endl <<
+                   indent() << "if err = done(err); !retry.Again(err) {" << endl <<
+                   indent() << "  return" << endl <<
+                   indent() << "}" << endl;
+        indent_down();
+        f_service_ <<
+                   indent() << "}" << endl;
         indent_down();
         f_service_ <<
                    indent() << "}" << endl << endl <<
@@ -1788,6 +1838,198 @@ void t_go_generator::generate_service_client(t_service* tservice)
                endl;
 }
 
+/**
+ * Generates a service client that can be shared between goroutines. The
+ * calls go through a thrift.TConcurrentClient, which matches replies to
+ * callers by seqid.
+ *
+ * @param tservice The service to generate a client for.
+ */
+void t_go_generator::generate_service_concurrent_client(t_service* tservice)
+{
+    string extends_client = "";
+    string extends_client_new = "";
+    string extends_field = "";
+    string serviceName(publicize(tservice->get_name()));
+
+    if (tservice->get_extends() != NULL) {
+        string extends = type_name(tservice->get_extends());
+        size_t index = extends.rfind(".");
+
+        if (index != string::npos) {
+            extends_client = extends.substr(0, index + 1) + publicize(extends.substr(index + 1)) + "Client";
+            extends_client_new = extends.substr(0, index + 1) + "New" + publicize(extends.substr(index + 1)) + "Client";
+            extends_field = publicize(extends.substr(index + 1)) + "Client";
+        } else {
+            extends_client = publicize(extends) + "Client";
+            extends_client_new = "New" + extends_client;
+            extends_field = extends_client;
+        }
+    }
+
+    generate_go_docstring(f_service_, tservice);
+    f_service_ <<
+               indent() << "type " << serviceName << "Client struct {" << endl;
+    indent_up();
+
+    if (!extends_client.empty()) {
+        f_service_ <<
+                   indent() << "*" << extends_client << endl;
+    } else {
+        f_service_ <<
+                   indent() << "*thrift.TConcurrentClient" << endl;
+    }
+
+    indent_down();
+    f_service_ <<
+               indent() << "}" << endl << endl;
+    // Constructor functions
+    f_service_ <<
+               indent() << "func New" << serviceName << "ClientFactory(t thrift.TTransport, f thrift.TProtocolFactory) *" << serviceName << "Client {" << endl;
+    indent_up();
+
+    if (!extends_client.empty()) {
+        f_service_ <<
+                   indent() << "return &" << serviceName << "Client{" << extends_field << ": " << extends_client_new << "Factory(t, f)}" << endl;
+    } else {
+        f_service_ <<
+                   indent() << "return &" << serviceName << "Client{TConcurrentClient: thrift.NewTConcurrentClientFactory(t, f)}" << endl;
+    }
+
+    indent_down();
+    f_service_ <<
+               indent() << "}" << endl << endl <<
+               indent() << "func New" << serviceName << "ClientProtocol(t thrift.TTransport, iprot thrift.TProtocol, oprot thrift.TProtocol) *" << serviceName << "Client {" << endl;
+    indent_up();
+
+    if (!extends_client.empty()) {
+        f_service_ <<
+                   indent() << "return &" << serviceName << "Client{" << extends_field << ": " << extends_client_new << "Protocol(t, iprot, oprot)}" << endl;
+    } else {
+        f_service_ <<
+                   indent() << "return &" << serviceName << "Client{TConcurrentClient: thrift.NewTConcurrentClientProtocol(t, iprot, oprot)}" << endl;
+    }
+
+    indent_down();
+    f_service_ <<
+               indent() << "}" << endl << endl;
+    // Generate client method implementations
+    vector<t_function*> functions = tservice->get_functions();
+    vector<t_function*>::const_iterator f_iter;
+
+    for (f_iter = functions.begin(); f_iter != functions.end(); ++f_iter) {
+        const vector<t_field*>& fields = (*f_iter)->get_arglist()->get_members();
+        vector<t_field*>::const_iterator fld_iter;
+        string funname(publicize((*f_iter)->get_name()));
+        string futurename(funname + "Future");
+        string escapedFuncName(escape_string((*f_iter)->get_name()));
+        string args(tmp("args"));
+        string params = "";
+
+        for (fld_iter = fields.begin(); fld_iter != fields.end(); ++fld_iter) {
+            if (fld_iter != fields.begin()) {
+                params += ", ";
+            }
+
+            params += variable_name_to_go_name((*fld_iter)->get_name());
+        }
+
+        generate_go_docstring(f_service_, (*f_iter));
+        f_service_ <<
+                   indent() << "func (p *" << serviceName << "Client) " << function_signature_if(*f_iter, "", true, gen_context_) << " {" << endl;
+        indent_up();
+
+        if ((*f_iter)->is_oneway()) {
+            f_service_ <<
+                       indent() << args << " := New" << publicize(privatize((*f_iter)->get_name()) + "Args") << "()" << endl;
+
+            for (fld_iter = fields.begin(); fld_iter != fields.end(); ++fld_iter) {
+                f_service_ <<
+                           indent() << args << "." << publicize(variable_name_to_go_name((*fld_iter)->get_name())) << " = " << variable_name_to_go_name((*fld_iter)->get_name()) << endl;
+            }
+
+            f_service_ <<
+                       indent() << "err = p." << (gen_context_ ? "OnewayContext(ctx, " : "Oneway(") << "\"" << escapedFuncName << "\", " << args << ")" << endl <<
+                       indent() << "return" << endl;
+            indent_down();
+            f_service_ <<
+                       indent() << "}" << endl << endl;
+            continue;
+        }
+
+        f_service_ <<
+                   indent() << "return p." << funname << "Async(" << (gen_context_ ? (params.empty() ? "ctx" : "ctx, ") : "") << params << ").Get()" << endl;
+        indent_down();
+        f_service_ <<
+                   indent() << "}" << endl << endl;
+        // The future holding the reply of an asynchronous call
+        string result(tmp("result"));
+        string resultname(publicize(privatize((*f_iter)->get_name()) + "Result"));
+        string async_args(argument_list((*f_iter)->get_arglist()));
+
+        if (gen_context_) {
+            async_args = (async_args.empty() ? "ctx context.Context" : "ctx context.Context, ") + async_args;
+        }
+
+        f_service_ <<
+                   indent() << "/**" << endl <<
+                   indent() << " * The pending reply of " << serviceName << "Client." << funname << "Async." << endl <<
+                   indent() << " */" << endl <<
+                   indent() << "type " << futurename << " struct {" << endl <<
+                   indent() << "  *thrift.TClientCall" << endl <<
+                   indent() << "  result *" << resultname << endl <<
+                   indent() << "}" << endl << endl <<
+                   indent() << "/**" << endl <<
+                   indent() << " * Waits for the reply and returns what " << funname << " would." << endl <<
+                   indent() << " */" << endl <<
+                   indent() << "func (p *" << futurename << ") Get() (" << function_results_if(*f_iter, tmp("retval"), true) << ") {" << endl;
+        indent_up();
+        f_service_ <<
+                   indent() << "if err = p.Wait(); err != nil {" << endl <<
+                   indent() << "  return" << endl <<
+                   indent() << "}" << endl <<
+                   indent() << "return ";
+
+        if (!(*f_iter)->get_returntype()->is_void()) {
+            f_service_ << "p.result.Success, ";
+        }
+
+        const vector<t_field*>& xceptions = (*f_iter)->get_xceptions()->get_members();
+        vector<t_field*>::const_iterator x_iter;
+
+        for (x_iter = xceptions.begin(); x_iter != xceptions.end(); ++x_iter) {
+            f_service_ << "p.result." << publicize(variable_name_to_go_name((*x_iter)->get_name())) << ", ";
+        }
+
+        f_service_ << "nil" << endl;
+        indent_down();
+        f_service_ <<
+                   indent() << "}" << endl << endl <<
+                   indent() << "/**" << endl <<
+                   indent() << " * Sends the " << escapedFuncName << " request without waiting for the reply." << endl <<
+                   indent() << " */" << endl <<
+                   indent() << "func (p *" << serviceName << "Client) " << funname << "Async(" << async_args << ") *" << futurename << " {" << endl;
+        indent_up();
+        f_service_ <<
+                   indent() << args << " := New" << publicize(privatize((*f_iter)->get_name()) + "Args") << "()" << endl;
+
+        for (fld_iter = fields.begin(); fld_iter != fields.end(); ++fld_iter) {
+            f_service_ <<
+                       indent() << args << "." << publicize(variable_name_to_go_name((*fld_iter)->get_name())) << " = " << variable_name_to_go_name((*fld_iter)->get_name()) << endl;
+        }
+
+        f_service_ <<
+                   indent() << result << " := New" << resultname << "()" << endl <<
+                   indent() << "return &" << futurename << "{TClientCall: p." << (gen_context_ ? "GoContext(ctx, " : "Go(") << "\"" << escapedFuncName << "\", " << args << ", " << result << ", nil), result: " << result << "}" << endl;
+        indent_down();
+        f_service_ <<
+                   indent() << "}" << endl << endl;
+    }
+
+    f_service_ <<
+               endl;
+}
+
 /**
  * Generates a command line tool for making remote requests
  *
@@ -1811,6 +2053,8 @@ void t_go_generator::generate_service_remote(t_service* tservice)
              go_autogen_comment() <<
              indent() << "package main" << endl << endl <<
              indent() << "import (" << endl <<
+             (gen_context_ && !functions.empty() ? indent() + "        \"context\"\n" : string()) <<
+             indent() << "        \"crypto/tls\"" << endl <<
              indent() << "        \"flag\"" << endl <<
              indent() << "        \"fmt\"" << endl <<
              indent() << "        \"math\"" << endl <<
@@ -1823,7 +2067,7 @@ void t_go_generator::generate_service_remote(t_service* tservice)
              indent() << ")" << endl <<
              indent() << endl <<
              indent() << "func Usage() {" << endl <<
-             indent() << "  fmt.Fprint(os.Stderr, \"Usage of \", os.Args[0], \" [-h host:port] [-u url] [-f[ramed]] function [arg1 [arg2...]]:\\n\")" << endl <<
+             indent() << "  fmt.Fprint(os.Stderr, \"Usage of \", os.Args[0], \" [-h host:port] [-u url] [-unix path] [-f[ramed]] [-buffered] [-ssl] function [arg1 [arg2...]]:\\n\")" << endl <<
              indent() << "  flag.PrintDefaults()" << endl <<
              indent() << "  fmt.Fprint(os.Stderr, \"Functions:\\n\")" << endl;
 
@@ -1847,8 +2091,11 @@ void t_go_generator::generate_service_remote(t_service* tservice)
              indent() << "var port int" << endl <<
              indent() << "var protocol string" << endl <<
              indent() << "var urlString string" << endl <<
+             indent() << "var unixPath string" << endl <<
              indent() << "var framed bool" << endl <<
+             indent() << "var buffered bool" << endl <<
              indent() << "var useHttp bool" << endl <<
+             indent() << "var useSsl bool" << endl <<
              indent() << "var help bool" << endl <<
              indent() << "var parsedUrl url.URL" << endl <<
              indent() << "var trans thrift.TTransport" << endl <<
@@ -1857,8 +2104,11 @@ void t_go_generator::generate_service_remote(t_service* tservice)
              indent() << "flag.IntVar(&port, \"p\", 9090, \"Specify port\")" << endl <<
              indent() << "flag.StringVar(&protocol, \"P\", \"binary\", \"Specify the protocol (binary, compact, simplejson, json)\")" << endl <<
              indent() << "flag.StringVar(&urlString, \"u\", \"\", \"Specify the url\")" << endl <<
+             indent() << "flag.StringVar(&unixPath, \"unix\", \"\", \"Specify the path of a Unix socket\")" << endl <<
              indent() << "flag.BoolVar(&framed, \"framed\", false, \"Use framed transport\")" << endl <<
+             indent() << "flag.BoolVar(&buffered, \"buffered\", false, \"Use buffered transport\")" << endl <<
              indent() << "flag.BoolVar(&useHttp, \"http\", false, \"Use http\")" << endl <<
+             indent() << "flag.BoolVar(&useSsl, \"ssl\", false, \"Use TLS\")" << endl <<
              indent() << "flag.BoolVar(&help, \"help\", false, \"See usage string\")" << endl <<
              indent() << "flag.Parse()" << endl <<
              indent() << "if help || flag.NArg() == 0 {" << endl <<
@@ -1888,14 +2138,25 @@ void t_go_generator::generate_service_remote(t_service* tservice)
              indent() << "if useHttp {" << endl <<
              indent() << "  trans, err = thrift.NewTHttpClient(parsedUrl.String())" << endl <<
              indent() << "} else {" << endl <<
-             indent() << "  addr, err := net.ResolveTCPAddr(\"tcp\", fmt.Sprint(host, \":\", port))" << endl <<
+             indent() << "  var addr net.Addr" << endl <<
+             indent() << "  if len(unixPath) > 0 {" << endl <<
+             indent() << "    addr, err = net.ResolveUnixAddr(\"unix\", unixPath)" << endl <<
+             indent() << "  } else {" << endl <<
+             indent() << "    addr, err = net.ResolveTCPAddr(\"tcp\", fmt.Sprint(host, \":\", port))" << endl <<
+             indent() << "  }" << endl <<
              indent() << "  if err != nil {" << endl <<
              indent() << "    fmt.Fprint(os.Stderr, \"Error resolving address\", err.Error())" << endl <<
              indent() << "    os.Exit(1)" << endl <<
              indent() << "  }" << endl <<
-             indent() << "  trans, err = thrift.NewTNonblockingSocketAddr(addr)" << endl <<
+             indent() << "  if useSsl {" << endl <<
+             indent() << "    trans = thrift.NewTSSLSocketAddr(addr, &tls.Config{ServerName: host})" << endl <<
+             indent() << "  } else {" << endl <<
+             indent() << "    trans, err = thrift.NewTNonblockingSocketAddr(addr)" << endl <<
+             indent() << "  }" << endl <<
              indent() << "  if framed {" << endl <<
              indent() << "    trans = thrift.NewTFramedTransport(trans)" << endl <<
+             indent() << "  } else if buffered {" << endl <<
+             indent() << "    trans = thrift.NewTBufferedTransport(trans)" << endl <<
              indent() << "  }" << endl <<
              indent() << "}" << endl <<
              indent() << "if err != nil {" << endl <<
@@ -2105,6 +2366,11 @@ void t_go_generator::generate_service_remote(t_service* tservice)
                  indent() << "fmt.Print(client." << pubName << "(";
         bool argFirst = true;
 
+        if (gen_context_) {
+            f_remote << "context.Background()";
+            argFirst = false;
+        }
+
         for (int i = 0; i < num_args; ++i) {
             if (argFirst) {
                 argFirst = false;
@@ -2329,7 +2595,28 @@ void t_go_generator::generate_process_function(t_service* tservice,
                indent() << "  oprot.Transport().Flush()" << endl <<
                indent() << "  return" << endl <<
                indent() << "}" << endl <<
-               indent() << "iprot.ReadMessageEnd()" << endl <<
+               indent() << "iprot.ReadMessageEnd()" << endl;
+
+    // middleware may refuse the call once it has seen the arguments
+    if (tfunction->is_oneway()) {
+        f_service_ <<
+                   indent() << "if thrift.RecordProcessorCallArgs(iprot, args) != nil {" << endl <<
+                   indent() << "  return true, nil" << endl <<
+                   indent() << "}" << endl;
+    } else {
+        f_service_ <<
+                   indent() << "if x := thrift.RecordProcessorCallArgs(iprot, args); x != nil {" << endl <<
+                   indent() << "  oprot.WriteMessageBegin(\"" << escape_string(tfunction->get_name()) << "\", thrift.EXCEPTION, seqId)" << endl <<
+                   indent() << "  x.Write(oprot)" << endl <<
+                   indent() << "  oprot.WriteMessageEnd()" << endl <<
+                   indent() << "  if err = oprot.Transport().Flush(); err != nil {" << endl <<
+                   indent() << "    return" << endl <<
+                   indent() << "  }" << endl <<
+                   indent() << "  return true, nil" << endl <<
+                   indent() << "}" << endl;
+    }
+
+    f_service_ <<
                indent() << "result := New" << resultname << "()" << endl <<
                indent() << "if ";
 
@@ -2355,6 +2642,11 @@ void t_go_generator::generate_process_function(t_service* tservice,
                "err = p.handler." << publicize(tfunction->get_name()) << "(";
     bool first = true;
 
+    if (gen_context_) {
+        f_service_ << "thrift.RequestContext(iprot)";
+        first = false;
+    }
+
     for (f_iter = fields.begin(); f_iter != fields.end(); ++f_iter) {
         if (first) {
             first = false;
@@ -2373,6 +2665,7 @@ void t_go_generator::generate_process_function(t_service* tservice,
                indent() << "  oprot.Transport().Flush()" << endl <<
                indent() << "  return" << endl <<
                indent() << "}" << endl <<
+               indent() << "thrift.RecordProcessorCallResult(iprot, result)" << endl <<
                indent() << "if err2 := oprot.WriteMessageBegin(\"" << escape_string(tfunction->get_name()) << "\", thrift.REPLY, seqId); err2 != nil {" << endl <<
                indent() << "  err = err2" << endl <<
                indent() << "}" << endl <<
@@ -3124,38 +3417,94 @@ string t_go_generator::function_signature(t_function* tfunction,
  */
 string t_go_generator::function_signature_if(t_function* tfunction,
         string prefix,
-        bool addOsError)
+        bool addOsError,
+        bool addContext,
+        string retval)
 {
     // TODO(mcslee): Nitpicky, no ',' if argument_list is empty
     string signature = publicize(prefix + tfunction->get_name()) + "(";
-    signature += argument_list(tfunction->get_arglist()) + ") (";
+    string args = argument_list(tfunction->get_arglist());
+
+    if (addContext) {
+        signature += args.empty() ? "ctx context.Context" : "ctx context.Context, ";
+    }
+
+    if (retval.empty()) {
+        retval = tmp("retval");
+    }
+
+    signature += args + ") (" + function_results_if(tfunction, retval, addOsError) + ")";
+    return signature;
+}
+
+/**
+ * Renders the named results of an interface function, without the
+ * parentheses around them, the return value being named retval
+ *
+ * @param tfunction Function definition
+ * @return String of rendered results
+ */
+string t_go_generator::function_results_if(t_function* tfunction,
+        string retval,
+        bool addOsError)
+{
+    string results;
     t_type* ret = tfunction->get_returntype();
     t_struct* exceptions = tfunction->get_xceptions();
     string errs = argument_list(exceptions);
-    string retval(tmp("retval"));
 
     if (!ret->is_void()) {
-        signature += retval + " " + type_to_go_type(ret);
+        results += retval + " " + type_to_go_type(ret);
 
         if (addOsError || errs.size() == 0) {
-            signature += ", ";
+            results += ", ";
         }
     }
 
     if (errs.size() > 0) {
-        signature += errs;
+        results += errs;
 
         if (addOsError) {
-            signature += ", ";
+            results += ", ";
         }
     }
 
     if (addOsError) {
-        signature += "err error";
+        results += "err error";
     }
 
-    signature += ")";
-    return signature;
+    return results;
+}
+
+/**
+ * Renders the names of the results of an interface function, as in
+ * 'retval, ouch, err'
+ *
+ * @param tfunction Function definition
+ * @return String of comma separated names
+ */
+string t_go_generator::function_result_names(t_function* tfunction,
+        string retval,
+        bool addOsError)
+{
+    string names;
+
+    if (!tfunction->get_returntype()->is_void()) {
+        names += retval;
+    }
+
+    const vector<t_field*>& xceptions = tfunction->get_xceptions()->get_members();
+    vector<t_field*>::const_iterator x_iter;
+
+    for (x_iter = xceptions.begin(); x_iter != xceptions.end(); ++x_iter) {
+        names += (names.empty() ? "" : ", ") + variable_name_to_go_name((*x_iter)->get_name());
+    }
+
+    if (addOsError) {
+        names += names.empty() ? "err" : ", err";
+    }
+
+    return names;
 }
 
 
@@ -3405,4 +3754,7 @@ bool format_go_output(const string &file_path)
 }
 
 
-THRIFT_REGISTER_GENERATOR(go, "Go", "");
+THRIFT_REGISTER_GENERATOR(go, "Go",
+                          "    concurrent_client: Generate clients that can be shared between goroutines,\n"
+                          "                       with <Method>Async calls returning futures.\n"
+                          "    context:         Pass a context.Context to handlers and client calls.\n");
@@ -0,0 +1,520 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"hash/crc32"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+/**
+ * How a TBalancer picks the endpoint of a call.
+ */
+type TBalancingPolicy int
+
+const (
+	// endpoints take calls in turn
+	BALANCE_ROUND_ROBIN TBalancingPolicy = iota
+	// the endpoint with the fewest calls outstanding takes the call
+	BALANCE_LEAST_OUTSTANDING
+	// calls with the same key go to the same endpoint while it is up
+	BALANCE_CONSISTENT_HASH
+)
+
+const (
+	DEFAULT_BALANCER_PROBE_INTERVAL = 5 * time.Second
+	// points each endpoint gets on the consistent hashing ring
+	BALANCER_HASH_REPLICAS = 100
+	// calls in a row that may fail on an open connection to an endpoint
+	// before it is taken out of rotation
+	BALANCER_MAX_FAILURES = 3
+)
+
+type tEndpoint struct {
+	addr        net.Addr
+	down        bool
+	outstanding int
+	failures    int
+}
+
+type tRingPoint struct {
+	hash     uint32
+	endpoint *tEndpoint
+}
+
+/**
+ * Spreads calls over a set of endpoints serving the same service.  Clients
+ * take part through transports made with NewTBalancingTransport; the
+ * balancer is shared between them, so that outstanding calls are counted
+ * across all of them.
+ *
+ * An endpoint is taken out of rotation when it cannot be dialed, or once
+ * BALANCER_MAX_FAILURES calls in a row failed reading or writing on an open
+ * connection to it, and is dialed every probe interval until it answers
+ * again.  A connection that fails, for instance because the server closed it
+ * while idle, is dropped and the next call to the endpoint dials it again.
+ * Calls fail with NOT_OPEN while every endpoint is down.
+ */
+type TBalancer struct {
+	newTransport func(addr net.Addr) TTransport
+	policy       TBalancingPolicy
+	endpoints    []*tEndpoint
+	ring         []tRingPoint
+
+	lock          sync.Mutex
+	next          int
+	probing       bool
+	probeInterval time.Duration
+	stop          chan bool
+}
+
+/**
+ * Creates a balancer over addrs, connecting to them through TSockets with
+ * the given timeout.
+ */
+func NewTBalancer(addrs []net.Addr, nsecTimeout int64, policy TBalancingPolicy) *TBalancer {
+	return NewTBalancerFunc(addrs, func(addr net.Addr) TTransport { return NewTSocket(addr, nsecTimeout) }, policy)
+}
+
+/**
+ * Creates a balancer over addrs, connecting to them by opening the
+ * transports returned by newTransport.
+ */
+func NewTBalancerFunc(addrs []net.Addr, newTransport func(addr net.Addr) TTransport, policy TBalancingPolicy) *TBalancer {
+	p := &TBalancer{
+		newTransport:  newTransport,
+		policy:        policy,
+		probeInterval: DEFAULT_BALANCER_PROBE_INTERVAL,
+		stop:          make(chan bool),
+	}
+	for _, addr := range addrs {
+		endpoint := &tEndpoint{addr: addr}
+		p.endpoints = append(p.endpoints, endpoint)
+		for i := 0; i < BALANCER_HASH_REPLICAS; i++ {
+			p.ring = append(p.ring, tRingPoint{hashKey(addr.String() + "#" + strconv.Itoa(i)), endpoint})
+		}
+	}
+	sort.Slice(p.ring, func(i, j int) bool { return p.ring[i].hash < p.ring[j].hash })
+	return p
+}
+
+func (p *TBalancer) Policy() TBalancingPolicy {
+	return p.policy
+}
+
+func (p *TBalancer) ProbeInterval() time.Duration {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.probeInterval
+}
+
+/**
+ * Sets how often endpoints that are down are dialed to see whether they
+ * can be brought back into rotation.
+ */
+func (p *TBalancer) SetProbeInterval(probeInterval time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.probeInterval = probeInterval
+}
+
+/**
+ * Returns the addresses of the endpoints in rotation.
+ */
+func (p *TBalancer) Up() []net.Addr {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var addrs []net.Addr
+	for _, endpoint := range p.endpoints {
+		if !endpoint.down {
+			addrs = append(addrs, endpoint.addr)
+		}
+	}
+	return addrs
+}
+
+/**
+ * Stops probing the endpoints that are down.  Transports already made keep
+ * working with the endpoints still up.
+ */
+func (p *TBalancer) Close() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	select {
+	case <-p.stop:
+	default:
+		close(p.stop)
+	}
+	return nil
+}
+
+func (p *TBalancer) pick(key string) *tEndpoint {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var picked *tEndpoint
+	switch p.policy {
+	case BALANCE_CONSISTENT_HASH:
+		h := hashKey(key)
+		start := sort.Search(len(p.ring), func(i int) bool { return p.ring[i].hash >= h })
+		for i := 0; i < len(p.ring); i++ {
+			point := p.ring[(start+i)%len(p.ring)]
+			if !point.endpoint.down {
+				picked = point.endpoint
+				break
+			}
+		}
+	default:
+		n := len(p.endpoints)
+		for i := 0; i < n; i++ {
+			endpoint := p.endpoints[(p.next+i)%n]
+			if endpoint.down {
+				continue
+			}
+			if picked == nil || (p.policy == BALANCE_LEAST_OUTSTANDING && endpoint.outstanding < picked.outstanding) {
+				picked = endpoint
+			}
+			if p.policy == BALANCE_ROUND_ROBIN {
+				break
+			}
+		}
+		if picked != nil {
+			for i, endpoint := range p.endpoints {
+				if endpoint == picked {
+					p.next = i + 1
+				}
+			}
+		}
+	}
+	if picked != nil {
+		picked.outstanding++
+	}
+	return picked
+}
+
+func (p *TBalancer) done(endpoint *tEndpoint) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	endpoint.outstanding--
+}
+
+/**
+ * Counts a call to endpoint that failed on an open connection, taking the
+ * endpoint out of rotation after BALANCER_MAX_FAILURES in a row.
+ */
+func (p *TBalancer) failed(endpoint *tEndpoint) {
+	p.lock.Lock()
+	endpoint.failures++
+	down := endpoint.failures >= BALANCER_MAX_FAILURES
+	p.lock.Unlock()
+	if down {
+		p.markDown(endpoint)
+	}
+}
+
+func (p *TBalancer) succeeded(endpoint *tEndpoint) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	endpoint.failures = 0
+}
+
+func (p *TBalancer) markDown(endpoint *tEndpoint) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	endpoint.down = true
+	if !p.probing {
+		p.probing = true
+		go p.probe()
+	}
+}
+
+/**
+ * Dials the endpoints that are down every probe interval, until they are
+ * all back up or the balancer is closed.
+ */
+func (p *TBalancer) probe() {
+	for {
+		select {
+		case <-time.After(p.ProbeInterval()):
+		case <-p.stop:
+			p.lock.Lock()
+			p.probing = false
+			p.lock.Unlock()
+			return
+		}
+		p.lock.Lock()
+		var down []*tEndpoint
+		for _, endpoint := range p.endpoints {
+			if endpoint.down {
+				down = append(down, endpoint)
+			}
+		}
+		if len(down) == 0 {
+			p.probing = false
+			p.lock.Unlock()
+			return
+		}
+		p.lock.Unlock()
+		for _, endpoint := range down {
+			transport := p.newTransport(endpoint.addr)
+			if transport.Open() == nil {
+				transport.Close()
+				p.lock.Lock()
+				endpoint.down = false
+				endpoint.failures = 0
+				p.lock.Unlock()
+			}
+		}
+	}
+}
+
+func hashKey(key string) uint32 {
+	return crc32.ChecksumIEEE([]byte(key))
+}
+
+/**
+ * Client side transport sending each call to an endpoint picked by a
+ * TBalancer, to be passed to a generated New<Service>ClientFactory like any
+ * other transport; wrapping transports such as TFramedTransport go on top.
+ * A connection to each endpoint is opened on first use and kept for the
+ * calls that follow.
+ *
+ * A call starts with the first write after the previous call was flushed
+ * and counts as outstanding until its reply starts to arrive, by which time
+ * the server is done with it.  Like the clients it serves, a
+ * TBalancingTransport must not be used by several goroutines at once; give
+ * each client its own.  Only SetDeadline may be called from another
+ * goroutine, as WatchContext does.
+ */
+type TBalancingTransport struct {
+	balancer    *TBalancer
+	key         string
+	current     *tEndpoint
+	outstanding bool
+	flushed     bool
+	open        bool
+	// guards conns and deadline against SetDeadline
+	lock     sync.Mutex
+	conns    map[*tEndpoint]TTransport
+	deadline time.Time
+}
+
+func NewTBalancingTransport(balancer *TBalancer) *TBalancingTransport {
+	return &TBalancingTransport{balancer: balancer, conns: make(map[*tEndpoint]TTransport)}
+}
+
+func (p *TBalancingTransport) Key() string {
+	return p.key
+}
+
+/**
+ * Sets the key the calls that follow are hashed on under
+ * BALANCE_CONSISTENT_HASH.
+ */
+func (p *TBalancingTransport) SetKey(key string) {
+	p.key = key
+}
+
+/**
+ * Returns the address of the endpoint of the current call, nil between
+ * calls.
+ */
+func (p *TBalancingTransport) Addr() net.Addr {
+	if p.current == nil {
+		return nil
+	}
+	return p.current.addr
+}
+
+/**
+ * Connections are only opened once a call is made.
+ */
+func (p *TBalancingTransport) Open() error {
+	if p.open {
+		return NewTTransportException(ALREADY_OPEN, "Balancing transport already open")
+	}
+	p.open = true
+	return nil
+}
+
+func (p *TBalancingTransport) IsOpen() bool {
+	return p.open
+}
+
+func (p *TBalancingTransport) Peek() bool {
+	if p.current == nil {
+		return p.open
+	}
+	return p.conn().Peek()
+}
+
+func (p *TBalancingTransport) Close() error {
+	p.release()
+	p.lock.Lock()
+	conns := p.conns
+	p.conns = make(map[*tEndpoint]TTransport)
+	p.lock.Unlock()
+	for _, conn := range conns {
+		conn.Close()
+	}
+	p.open = false
+	return nil
+}
+
+func (p *TBalancingTransport) Read(buf []byte) (int, error) {
+	if p.current == nil {
+		return 0, NewTTransportException(NOT_OPEN, "No call in progress")
+	}
+	n, err := p.conn().Read(buf)
+	if err != nil {
+		p.fail()
+	} else if n > 0 && p.flushed && p.outstanding {
+		// the reply is coming, so the server is done with the call
+		p.balancer.done(p.current)
+		p.balancer.succeeded(p.current)
+		p.outstanding = false
+	}
+	return n, err
+}
+
+func (p *TBalancingTransport) ReadAll(buf []byte) (int, error) {
+	return ReadAllTransport(p, buf)
+}
+
+func (p *TBalancingTransport) Write(buf []byte) (int, error) {
+	if p.current == nil || p.flushed {
+		if err := p.begin(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := p.conn().Write(buf)
+	if err != nil {
+		p.fail()
+	}
+	return n, err
+}
+
+func (p *TBalancingTransport) Flush() error {
+	if p.current == nil {
+		return nil
+	}
+	if err := p.conn().Flush(); err != nil {
+		p.fail()
+		return err
+	}
+	p.flushed = true
+	return nil
+}
+
+/**
+ * Sets the deadline on the connections to every endpoint, including those
+ * opened later.
+ */
+func (p *TBalancingTransport) SetDeadline(deadline time.Time) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.deadline = deadline
+	for _, conn := range p.conns {
+		if err := setTransportDeadline(conn, deadline); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/**
+ * Picks the endpoint of a new call and makes sure there is a connection to
+ * it, taking endpoints that cannot be dialed out of rotation.
+ */
+func (p *TBalancingTransport) begin() error {
+	if !p.open {
+		return NewTTransportException(NOT_OPEN, "Balancing transport not open")
+	}
+	p.release()
+	for {
+		endpoint := p.balancer.pick(p.key)
+		if endpoint == nil {
+			return NewTTransportException(NOT_OPEN, "No endpoint available")
+		}
+		p.lock.Lock()
+		_, ok := p.conns[endpoint]
+		p.lock.Unlock()
+		if ok {
+			p.current = endpoint
+			p.outstanding = true
+			return nil
+		}
+		conn := p.balancer.newTransport(endpoint.addr)
+		p.lock.Lock()
+		deadline := p.deadline
+		p.lock.Unlock()
+		if !deadline.IsZero() {
+			setTransportDeadline(conn, deadline)
+		}
+		if err := conn.Open(); err != nil {
+			p.balancer.done(endpoint)
+			p.balancer.markDown(endpoint)
+			continue
+		}
+		p.lock.Lock()
+		if !p.deadline.Equal(deadline) {
+			// moved while dialing
+			setTransportDeadline(conn, p.deadline)
+		}
+		p.conns[endpoint] = conn
+		p.lock.Unlock()
+		p.current = endpoint
+		p.outstanding = true
+		return nil
+	}
+}
+
+func (p *TBalancingTransport) conn() TTransport {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.conns[p.current]
+}
+
+func (p *TBalancingTransport) release() {
+	if p.outstanding {
+		p.balancer.done(p.current)
+		p.outstanding = false
+	}
+	p.current = nil
+	p.flushed = false
+}
+
+/**
+ * Drops the connection of the current call after an error reading or
+ * writing it, and counts the failure against the endpoint.  Until the
+ * endpoint is taken out, the next call to it dials again.
+ */
+func (p *TBalancingTransport) fail() {
+	p.balancer.failed(p.current)
+	p.lock.Lock()
+	conn := p.conns[p.current]
+	delete(p.conns, p.current)
+	p.lock.Unlock()
+	conn.Close()
+	p.release()
+}
@@ -0,0 +1,226 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func startBalancerTestServers(t *testing.T, n int) ([]*TNonblockingServer, []net.Addr) {
+	var servers []*TNonblockingServer
+	var addrs []net.Addr
+	for i := 0; i < n; i++ {
+		server, addr := startTestServer(t)
+		servers = append(servers, server)
+		addrs = append(addrs, addr)
+	}
+	return servers, addrs
+}
+
+func stopBalancerTestServers(servers []*TNonblockingServer) {
+	for _, server := range servers {
+		server.Stop()
+	}
+}
+
+/**
+ * Makes a call through trans and returns the address it went to.
+ */
+func pingBalanced(t *testing.T, trans *TBalancingTransport) net.Addr {
+	prot := NewTBinaryProtocolTransport(trans)
+	if err := pingCall(prot); err != nil {
+		t.Fatalf("Expected the call to succeed, but found %s", err)
+	}
+	return trans.Addr()
+}
+
+func TestBalancingTransportRoundRobin(t *testing.T) {
+	servers, addrs := startBalancerTestServers(t, 3)
+	defer stopBalancerTestServers(servers)
+	balancer := NewTBalancer(addrs, 5e9, BALANCE_ROUND_ROBIN)
+	defer balancer.Close()
+	trans := NewTBalancingTransport(balancer)
+	trans.Open()
+	defer trans.Close()
+
+	for i := 0; i < 6; i++ {
+		if addr := pingBalanced(t, trans); addr != addrs[i%3] {
+			t.Fatalf("Expected call %d to go to %s, but it went to %s", i, addrs[i%3], addr)
+		}
+	}
+}
+
+func TestBalancingTransportLeastOutstanding(t *testing.T) {
+	servers, addrs := startBalancerTestServers(t, 2)
+	defer stopBalancerTestServers(servers)
+	balancer := NewTBalancer(addrs, 5e9, BALANCE_LEAST_OUTSTANDING)
+	defer balancer.Close()
+	busy := NewTBalancingTransport(balancer)
+	busy.Open()
+	defer busy.Close()
+	other := NewTBalancingTransport(balancer)
+	other.Open()
+	defer other.Close()
+
+	// a call whose reply has not been read yet
+	busyProt := NewTBinaryProtocolTransport(busy)
+	writeEmptyCall(t, busyProt, "ping", 1)
+	busyAddr := busy.Addr()
+	for i := 0; i < 3; i++ {
+		if addr := pingBalanced(t, other); addr == busyAddr {
+			t.Fatalf("Expected calls to avoid the endpoint with a call outstanding")
+		}
+	}
+
+	if _, _, _, err := busyProt.ReadMessageBegin(); err != nil {
+		t.Fatalf("Unable to read the reply: %s", err)
+	}
+	busyProt.Skip(STRUCT)
+	busyProt.ReadMessageEnd()
+	used := make(map[string]bool)
+	for i := 0; i < 2; i++ {
+		used[pingBalanced(t, other).String()] = true
+	}
+	if !used[busyAddr.String()] {
+		t.Fatalf("Expected the endpoint to take calls again once its reply was read")
+	}
+}
+
+func TestBalancingTransportConsistentHash(t *testing.T) {
+	servers, addrs := startBalancerTestServers(t, 3)
+	defer stopBalancerTestServers(servers)
+	balancer := NewTBalancer(addrs, 5e9, BALANCE_CONSISTENT_HASH)
+	defer balancer.Close()
+	trans := NewTBalancingTransport(balancer)
+	trans.Open()
+	defer trans.Close()
+
+	seen := make(map[string]bool)
+	for _, key := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		trans.SetKey(key)
+		first := pingBalanced(t, trans)
+		if again := pingBalanced(t, trans); again != first {
+			t.Fatalf("Expected key %s to stick to %s, but it moved to %s", key, first, again)
+		}
+		seen[first.String()] = true
+	}
+	if len(seen) < 2 {
+		t.Fatalf("Expected keys to be spread over the endpoints")
+	}
+}
+
+func TestBalancingTransportMarksEndpointsDown(t *testing.T) {
+	servers, addrs := startBalancerTestServers(t, 2)
+	defer stopBalancerTestServers(servers)
+	balancer := NewTBalancer(addrs, 5e9, BALANCE_ROUND_ROBIN)
+	defer balancer.Close()
+	balancer.SetProbeInterval(10 * time.Millisecond)
+	trans := NewTBalancingTransport(balancer)
+	trans.Open()
+	defer trans.Close()
+
+	pingBalanced(t, trans)
+	pingBalanced(t, trans)
+	servers[0].Shutdown(5e9)
+	for i := 0; i < 4; i++ {
+		if err := pingCall(NewTBinaryProtocolTransport(trans)); err == nil && trans.Addr() != addrs[1] {
+			t.Fatalf("Expected calls to go to the endpoint still up, but one went to %s", trans.Addr())
+		}
+	}
+	if up := balancer.Up(); len(up) != 1 || up[0] != addrs[1] {
+		t.Fatalf("Expected only %s to be up, but found %v", addrs[1], up)
+	}
+
+	serverTransport, err := NewTServerSocketAddrTimeout(addrs[0], 5e9)
+	if err != nil {
+		t.Fatalf("Unable to create server socket: %s", err)
+	}
+	if err := serverTransport.Listen(); err != nil {
+		t.Fatalf("Unable to listen again on %s: %s", addrs[0], err)
+	}
+	servers[0] = NewTNonblockingServer2(&recordingProcessor{}, serverTransport)
+	go servers[0].Serve()
+	for deadline := time.Now().Add(5 * time.Second); len(balancer.Up()) != 2; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the restarted endpoint to be probed back in")
+		}
+	}
+}
+
+func TestBalancingTransportKeepsEndpointAfterConnectionFailure(t *testing.T) {
+	servers, addrs := startBalancerTestServers(t, 1)
+	defer stopBalancerTestServers(servers)
+	balancer := NewTBalancer(addrs, 5e9, BALANCE_ROUND_ROBIN)
+	defer balancer.Close()
+	trans := NewTBalancingTransport(balancer)
+	trans.Open()
+	defer trans.Close()
+
+	pingBalanced(t, trans)
+	for _, conn := range trans.conns {
+		// as if the server had dropped the idle connection
+		conn.Close()
+	}
+	pingCall(NewTBinaryProtocolTransport(trans))
+	if up := balancer.Up(); len(up) != 1 {
+		t.Fatalf("Expected the endpoint to stay in rotation, but found %v", up)
+	}
+	pingBalanced(t, trans)
+}
+
+func TestBalancingTransportMarksFailingEndpointDown(t *testing.T) {
+	servers, addrs := startBalancerTestServers(t, 1)
+	defer stopBalancerTestServers(servers)
+	// accepts connections but hangs up on every call
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen: %s", err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	balancer := NewTBalancer([]net.Addr{addrs[0], l.Addr()}, 5e9, BALANCE_ROUND_ROBIN)
+	defer balancer.Close()
+	balancer.SetProbeInterval(200 * time.Millisecond)
+	trans := NewTBalancingTransport(balancer)
+	trans.Open()
+	defer trans.Close()
+
+	for i := 0; i < 2*BALANCER_MAX_FAILURES; i++ {
+		pingCall(NewTBinaryProtocolTransport(trans))
+	}
+	if up := balancer.Up(); len(up) != 1 || up[0] != addrs[0] {
+		t.Fatalf("Expected only %s to be up after %d failed calls to %s, but found %v", addrs[0], BALANCER_MAX_FAILURES, l.Addr(), up)
+	}
+	for deadline := time.Now().Add(5 * time.Second); len(balancer.Up()) != 2; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the failing endpoint to be probed back in")
+		}
+	}
+}
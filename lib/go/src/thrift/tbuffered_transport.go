@@ -0,0 +1,128 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"bufio"
+	"time"
+)
+
+const DEFAULT_BUFFERED_TRANSPORT_SIZE = 4096
+
+/**
+ * Buffered transport.  Reads are served from a read buffer that is refilled
+ * from the underlying transport in large chunks, and writes are collected in
+ * a write buffer that is only written out to the underlying transport when it
+ * fills up or on Flush().
+ */
+type TBufferedTransport struct {
+	transport TTransport
+	reader    *bufio.Reader
+	writer    *bufio.Writer
+}
+
+type tBufferedTransportFactory struct {
+	factory         TTransportFactory
+	readBufferSize  int
+	writeBufferSize int
+}
+
+func NewTBufferedTransportFactory(factory TTransportFactory) TTransportFactory {
+	return NewTBufferedTransportFactorySize(factory, DEFAULT_BUFFERED_TRANSPORT_SIZE, DEFAULT_BUFFERED_TRANSPORT_SIZE)
+}
+
+func NewTBufferedTransportFactorySize(factory TTransportFactory, readBufferSize, writeBufferSize int) TTransportFactory {
+	return &tBufferedTransportFactory{factory: factory, readBufferSize: readBufferSize, writeBufferSize: writeBufferSize}
+}
+
+func (p *tBufferedTransportFactory) GetTransport(base TTransport) TTransport {
+	return NewTBufferedTransportSize(p.factory.GetTransport(base), p.readBufferSize, p.writeBufferSize)
+}
+
+func NewTBufferedTransport(transport TTransport) *TBufferedTransport {
+	return NewTBufferedTransportSize(transport, DEFAULT_BUFFERED_TRANSPORT_SIZE, DEFAULT_BUFFERED_TRANSPORT_SIZE)
+}
+
+func NewTBufferedTransportSize(transport TTransport, readBufferSize, writeBufferSize int) *TBufferedTransport {
+	return &TBufferedTransport{
+		transport: transport,
+		reader:    bufio.NewReaderSize(transport, readBufferSize),
+		writer:    bufio.NewWriterSize(transport, writeBufferSize),
+	}
+}
+
+/**
+ * Opens the transport underneath.  Buffered data and errors left from
+ * before are dropped, as the buffers would otherwise keep failing.
+ */
+func (p *TBufferedTransport) Open() error {
+	p.reset()
+	return p.transport.Open()
+}
+
+func (p *TBufferedTransport) IsOpen() bool {
+	return p.transport.IsOpen()
+}
+
+func (p *TBufferedTransport) Peek() bool {
+	return p.reader.Buffered() > 0 || p.transport.Peek()
+}
+
+/**
+ * Closes the transport underneath, dropping what was written but not
+ * flushed and what was read ahead.
+ */
+func (p *TBufferedTransport) Close() error {
+	p.reset()
+	return p.transport.Close()
+}
+
+func (p *TBufferedTransport) reset() {
+	p.reader.Reset(p.transport)
+	p.writer.Reset(p.transport)
+}
+
+func (p *TBufferedTransport) SetDeadline(deadline time.Time) error {
+	return setTransportDeadline(p.transport, deadline)
+}
+
+func (p *TBufferedTransport) Read(buf []byte) (int, error) {
+	n, err := p.reader.Read(buf)
+	return n, NewTTransportExceptionFromOsError(err)
+}
+
+func (p *TBufferedTransport) ReadAll(buf []byte) (int, error) {
+	return ReadAllTransport(p, buf)
+}
+
+func (p *TBufferedTransport) Write(buf []byte) (int, error) {
+	n, err := p.writer.Write(buf)
+	return n, NewTTransportExceptionFromOsError(err)
+}
+
+/**
+ * Writes out any buffered data and flushes the underlying transport.
+ */
+func (p *TBufferedTransport) Flush() error {
+	if err := p.writer.Flush(); err != nil {
+		return NewTTransportExceptionFromOsError(err)
+	}
+	return NewTTransportExceptionFromOsError(p.transport.Flush())
+}
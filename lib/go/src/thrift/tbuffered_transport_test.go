@@ -0,0 +1,82 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"testing"
+)
+
+func TestBufferedTransport(t *testing.T) {
+	trans := NewTBufferedTransportSize(NewTMemoryBuffer(), 100, 10240)
+	TransportTest(t, trans, trans)
+}
+
+func TestBufferedTransportHoldsWritesUntilFlush(t *testing.T) {
+	buf := NewTMemoryBuffer()
+	trans := NewTBufferedTransport(buf)
+	trans.Write([]byte{1, 2, 3, 4})
+	if buf.Len() != 0 {
+		t.Fatalf("Expected no bytes written before flush, but found %d", buf.Len())
+	}
+	if err := trans.Flush(); err != nil {
+		t.Fatalf("Unable to flush: %s", err)
+	}
+	if buf.Len() != 4 {
+		t.Fatalf("Expected 4 bytes written after flush, but found %d", buf.Len())
+	}
+}
+
+/**
+ * Memory transport whose writes fail while broken.
+ */
+type breakableMemoryTransport struct {
+	*TMemoryBuffer
+	broken bool
+}
+
+func (p *breakableMemoryTransport) Write(buf []byte) (int, error) {
+	if p.broken {
+		return 0, NewTTransportException(UNKNOWN_TRANSPORT_EXCEPTION, "broken")
+	}
+	return p.TMemoryBuffer.Write(buf)
+}
+
+func TestBufferedTransportReopensAfterFailure(t *testing.T) {
+	buf := &breakableMemoryTransport{TMemoryBuffer: NewTMemoryBuffer(), broken: true}
+	trans := NewTBufferedTransportSize(buf, 4, 4)
+	trans.Write([]byte{1, 2, 3, 4, 5, 6, 7, 8})
+	if err := trans.Flush(); err == nil {
+		t.Fatalf("Expected flushing onto a broken transport to fail")
+	}
+	trans.Close()
+	buf.broken = false
+	if err := trans.Open(); err != nil {
+		t.Fatalf("Unable to reopen: %s", err)
+	}
+	if _, err := trans.Write([]byte{9, 10}); err != nil {
+		t.Fatalf("Unable to write after reopening: %s", err)
+	}
+	if err := trans.Flush(); err != nil {
+		t.Fatalf("Unable to flush after reopening: %s", err)
+	}
+	if buf.Len() != 2 {
+		t.Fatalf("Expected only the 2 bytes written after reopening, but found %d", buf.Len())
+	}
+}
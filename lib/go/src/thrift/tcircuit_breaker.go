@@ -0,0 +1,409 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"sync"
+	"time"
+)
+
+type TCircuitState int
+
+const (
+	// calls go through
+	CIRCUIT_CLOSED TCircuitState = iota
+	// calls fail fast with CIRCUIT_BREAKER_OPEN
+	CIRCUIT_OPEN
+	// a single call goes through to probe the server
+	CIRCUIT_HALF_OPEN
+)
+
+const (
+	DEFAULT_CIRCUIT_FAILURE_RATIO = 0.5
+	DEFAULT_CIRCUIT_MIN_CALLS     = 10
+	DEFAULT_CIRCUIT_WINDOW        = 10 * time.Second
+	DEFAULT_CIRCUIT_OPEN_TIMEOUT  = 5 * time.Second
+)
+
+func (p TCircuitState) String() string {
+	switch p {
+	case CIRCUIT_CLOSED:
+		return "closed"
+	case CIRCUIT_OPEN:
+		return "open"
+	case CIRCUIT_HALF_OPEN:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+/**
+ * Stops calls to a server that keeps failing, so that it gets a chance to
+ * recover and callers do not wait on it in vain.  Calls are counted over a
+ * window of time; once at least MinCalls were made and the share of them
+ * that failed with a transport error, timeouts included, reaches
+ * FailureRatio, the circuit opens and calls fail with a CIRCUIT_BREAKER_OPEN
+ * TTransportException without reaching the server.  After OpenTimeout the
+ * circuit half-opens and lets a single call through: it closes again if
+ * that call succeeds and opens for another OpenTimeout if it fails.
+ *
+ * Calls take part through transports made with
+ * NewTCircuitBreakerTransport, which may share a breaker.
+ */
+type TCircuitBreaker struct {
+	lock         sync.Mutex
+	state        TCircuitState
+	failureRatio float64
+	minCalls     int
+	window       time.Duration
+	openTimeout  time.Duration
+	windowStart  time.Time
+	calls        int
+	failures     int
+	openedAt     time.Time
+	probing      bool
+}
+
+func NewTCircuitBreaker() *TCircuitBreaker {
+	return &TCircuitBreaker{
+		failureRatio: DEFAULT_CIRCUIT_FAILURE_RATIO,
+		minCalls:     DEFAULT_CIRCUIT_MIN_CALLS,
+		window:       DEFAULT_CIRCUIT_WINDOW,
+		openTimeout:  DEFAULT_CIRCUIT_OPEN_TIMEOUT,
+	}
+}
+
+func (p *TCircuitBreaker) FailureRatio() float64 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.failureRatio
+}
+
+/**
+ * Sets the share of failed calls, between 0 and 1, at which the circuit
+ * opens.
+ */
+func (p *TCircuitBreaker) SetFailureRatio(failureRatio float64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.failureRatio = failureRatio
+}
+
+func (p *TCircuitBreaker) MinCalls() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.minCalls
+}
+
+/**
+ * Sets how many calls a window must have seen before the circuit can open.
+ */
+func (p *TCircuitBreaker) SetMinCalls(minCalls int) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.minCalls = minCalls
+}
+
+func (p *TCircuitBreaker) Window() time.Duration {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.window
+}
+
+/**
+ * Sets how long calls are counted for before the counts start over.
+ */
+func (p *TCircuitBreaker) SetWindow(window time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.window = window
+}
+
+func (p *TCircuitBreaker) OpenTimeout() time.Duration {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.openTimeout
+}
+
+/**
+ * Sets how long the circuit stays open before letting a call through.
+ */
+func (p *TCircuitBreaker) SetOpenTimeout(openTimeout time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.openTimeout = openTimeout
+}
+
+func (p *TCircuitBreaker) State() TCircuitState {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.state == CIRCUIT_OPEN && time.Since(p.openedAt) >= p.openTimeout {
+		return CIRCUIT_HALF_OPEN
+	}
+	return p.state
+}
+
+/**
+ * Lets a call through or fails it fast.  probe is set for the call a
+ * half-open circuit lets through, whose outcome decides the state.
+ */
+func (p *TCircuitBreaker) admit() (probe bool, err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.state == CIRCUIT_OPEN && time.Since(p.openedAt) >= p.openTimeout {
+		p.state = CIRCUIT_HALF_OPEN
+		p.probing = false
+	}
+	switch p.state {
+	case CIRCUIT_CLOSED:
+		return false, nil
+	case CIRCUIT_HALF_OPEN:
+		if !p.probing {
+			p.probing = true
+			return true, nil
+		}
+	}
+	return false, NewTTransportException(CIRCUIT_BREAKER_OPEN, "Circuit breaker open")
+}
+
+func (p *TCircuitBreaker) done(probe bool, failed bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	now := time.Now()
+	if probe {
+		p.probing = false
+		if failed {
+			p.trip(now)
+		} else {
+			p.state = CIRCUIT_CLOSED
+			p.windowStart = now
+			p.calls = 0
+			p.failures = 0
+		}
+		return
+	}
+	if p.state != CIRCUIT_CLOSED {
+		// calls let through before the circuit opened
+		return
+	}
+	if now.Sub(p.windowStart) >= p.window {
+		p.windowStart = now
+		p.calls = 0
+		p.failures = 0
+	}
+	p.calls++
+	if failed {
+		p.failures++
+	}
+	if p.calls >= p.minCalls && float64(p.failures) >= p.failureRatio*float64(p.calls) {
+		p.trip(now)
+	}
+}
+
+/**
+ * Lets the probe slot go after a call ended without an outcome.
+ */
+func (p *TCircuitBreaker) abandon(probe bool) {
+	if probe {
+		p.lock.Lock()
+		p.probing = false
+		p.lock.Unlock()
+	}
+}
+
+func (p *TCircuitBreaker) trip(now time.Time) {
+	p.state = CIRCUIT_OPEN
+	p.openedAt = now
+}
+
+/**
+ * Client side transport putting the calls made over it through a
+ * TCircuitBreaker, to be wrapped by TFramedTransport and the like and passed
+ * to a generated New<Service>ClientFactory.  A call starts with the first
+ * write after the previous call was flushed, which fails fast while the
+ * circuit is open.  It succeeds once the reply starts coming in, or, for a
+ * oneway call, once the next call starts, and fails on the first transport
+ * error before that.  A call cut short by Close counts neither way.
+ *
+ * Calls are told apart by the order of writes and reads, so the transport
+ * only serves clients that wait for each reply before the next call, such
+ * as those generated by default.  Concurrent clients, which write further
+ * calls while reading replies, are refused: a Read, Write or Flush made
+ * while another is in progress fails.  Give each concurrent client a
+ * breaker of its own at a higher level instead.  Close may be called from
+ * any goroutine.
+ */
+type TCircuitBreakerTransport struct {
+	transport TTransport
+	breaker   *TCircuitBreaker
+	// guards the state of the current call
+	lock    sync.Mutex
+	busy    bool
+	inCall  bool
+	probe   bool
+	flushed bool
+}
+
+func NewTCircuitBreakerTransport(transport TTransport, breaker *TCircuitBreaker) *TCircuitBreakerTransport {
+	return &TCircuitBreakerTransport{transport: transport, breaker: breaker}
+}
+
+func (p *TCircuitBreakerTransport) Transport() TTransport {
+	return p.transport
+}
+
+func (p *TCircuitBreakerTransport) Breaker() *TCircuitBreaker {
+	return p.breaker
+}
+
+func (p *TCircuitBreakerTransport) Open() error {
+	return p.transport.Open()
+}
+
+func (p *TCircuitBreakerTransport) IsOpen() bool {
+	return p.transport.IsOpen()
+}
+
+func (p *TCircuitBreakerTransport) Peek() bool {
+	return p.transport.Peek()
+}
+
+func (p *TCircuitBreakerTransport) Close() error {
+	p.lock.Lock()
+	if p.inCall {
+		p.breaker.abandon(p.probe)
+		p.inCall = false
+		p.probe = false
+		p.flushed = false
+	}
+	p.lock.Unlock()
+	return p.transport.Close()
+}
+
+func (p *TCircuitBreakerTransport) Read(buf []byte) (int, error) {
+	if err := p.enter(); err != nil {
+		return 0, err
+	}
+	defer p.leave()
+	n, err := p.transport.Read(buf)
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if err != nil {
+		p.record(err)
+	} else if n > 0 && p.flushed {
+		p.record(nil)
+	}
+	return n, err
+}
+
+func (p *TCircuitBreakerTransport) ReadAll(buf []byte) (int, error) {
+	return ReadAllTransport(p, buf)
+}
+
+func (p *TCircuitBreakerTransport) Write(buf []byte) (int, error) {
+	if err := p.enter(); err != nil {
+		return 0, err
+	}
+	defer p.leave()
+	if err := p.begin(); err != nil {
+		return 0, err
+	}
+	n, err := p.transport.Write(buf)
+	if err != nil {
+		p.lock.Lock()
+		p.record(err)
+		p.lock.Unlock()
+	}
+	return n, err
+}
+
+func (p *TCircuitBreakerTransport) Flush() error {
+	if err := p.enter(); err != nil {
+		return err
+	}
+	defer p.leave()
+	err := p.transport.Flush()
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if err != nil {
+		p.record(err)
+	} else if p.inCall {
+		p.flushed = true
+	}
+	return err
+}
+
+func (p *TCircuitBreakerTransport) SetDeadline(deadline time.Time) error {
+	return setTransportDeadline(p.transport, deadline)
+}
+
+/**
+ * Marks a Read, Write or Flush as in progress, failing if another one is.
+ */
+func (p *TCircuitBreakerTransport) enter() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.busy {
+		return NewTTransportException(UNKNOWN_TRANSPORT_EXCEPTION, "Circuit breaker transport used by several goroutines at once")
+	}
+	p.busy = true
+	return nil
+}
+
+func (p *TCircuitBreakerTransport) leave() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.busy = false
+}
+
+/**
+ * Starts a call on the first write after the previous one was flushed,
+ * unless the breaker fails it fast.
+ */
+func (p *TCircuitBreakerTransport) begin() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.inCall && !p.flushed {
+		return nil
+	}
+	// a call flushed without a reply was oneway
+	p.record(nil)
+	probe, err := p.breaker.admit()
+	if err != nil {
+		return err
+	}
+	p.inCall = true
+	p.probe = probe
+	return nil
+}
+
+/**
+ * Hands the outcome of the current call, if any, to the breaker.  Must be
+ * called with the lock held.
+ */
+func (p *TCircuitBreakerTransport) record(err error) {
+	if !p.inCall {
+		return
+	}
+	p.breaker.done(p.probe, err != nil)
+	p.inCall = false
+	p.probe = false
+	p.flushed = false
+}
@@ -0,0 +1,185 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"testing"
+	"time"
+)
+
+/**
+ * Transport standing in for a server that either answers every call or
+ * times out on all of them.
+ */
+type flakyTransport struct {
+	*TMemoryBuffer
+	failing bool
+	writes  int
+}
+
+func (p *flakyTransport) Write(buf []byte) (int, error) {
+	p.writes++
+	return len(buf), nil
+}
+
+func (p *flakyTransport) Flush() error {
+	return nil
+}
+
+func (p *flakyTransport) Read(buf []byte) (int, error) {
+	if p.failing {
+		return 0, NewTTransportException(TIMED_OUT, "timed out")
+	}
+	return len(buf), nil
+}
+
+func callThrough(trans TTransport) error {
+	if _, err := trans.Write([]byte{1}); err != nil {
+		return err
+	}
+	if err := trans.Flush(); err != nil {
+		return err
+	}
+	_, err := trans.Read(make([]byte, 1))
+	return err
+}
+
+func newTestCircuitBreaker() *TCircuitBreaker {
+	breaker := NewTCircuitBreaker()
+	breaker.SetMinCalls(4)
+	breaker.SetFailureRatio(0.5)
+	breaker.SetOpenTimeout(20 * time.Millisecond)
+	return breaker
+}
+
+func TestCircuitBreakerOpensAndFailsFast(t *testing.T) {
+	server := &flakyTransport{TMemoryBuffer: NewTMemoryBuffer()}
+	breaker := newTestCircuitBreaker()
+	trans := NewTCircuitBreakerTransport(server, breaker)
+
+	callThrough(trans)
+	callThrough(trans)
+	server.failing = true
+	callThrough(trans)
+	if breaker.State() != CIRCUIT_CLOSED {
+		t.Fatalf("Expected the circuit to stay closed below the minimum number of calls")
+	}
+	callThrough(trans)
+	if breaker.State() != CIRCUIT_OPEN {
+		t.Fatalf("Expected the circuit to open at half the calls failing, but found %s", breaker.State())
+	}
+	writes := server.writes
+	err := callThrough(trans)
+	if e, ok := err.(TTransportException); !ok || e.TypeId() != CIRCUIT_BREAKER_OPEN {
+		t.Fatalf("Expected CIRCUIT_BREAKER_OPEN, but found %v", err)
+	}
+	if server.writes != writes {
+		t.Fatalf("Expected a call failing fast not to reach the server")
+	}
+}
+
+func TestCircuitBreakerHalfOpens(t *testing.T) {
+	server := &flakyTransport{TMemoryBuffer: NewTMemoryBuffer(), failing: true}
+	breaker := newTestCircuitBreaker()
+	trans := NewTCircuitBreakerTransport(server, breaker)
+	other := NewTCircuitBreakerTransport(server, breaker)
+	for i := 0; i < 4; i++ {
+		callThrough(trans)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if breaker.State() != CIRCUIT_HALF_OPEN {
+		t.Fatalf("Expected the circuit to half-open after the open timeout, but found %s", breaker.State())
+	}
+	if err := callThrough(trans); err == nil {
+		t.Fatalf("Expected the probe to reach the failing server")
+	}
+	if breaker.State() != CIRCUIT_OPEN {
+		t.Fatalf("Expected a failed probe to open the circuit again, but found %s", breaker.State())
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	server.failing = false
+	trans.Write([]byte{1})
+	if err := callThrough(other); err == nil {
+		t.Fatalf("Expected a second call to fail fast while the probe is out")
+	}
+	trans.Flush()
+	trans.Read(make([]byte, 1))
+	if breaker.State() != CIRCUIT_CLOSED {
+		t.Fatalf("Expected a successful probe to close the circuit, but found %s", breaker.State())
+	}
+	if err := callThrough(other); err != nil {
+		t.Fatalf("Expected calls to go through again, but found %s", err)
+	}
+}
+
+/**
+ * Transport whose reads wait for a reply to be released.
+ */
+type blockingReadTransport struct {
+	*flakyTransport
+	reading chan bool
+	reply   chan bool
+}
+
+func (p *blockingReadTransport) Read(buf []byte) (int, error) {
+	p.reading <- true
+	<-p.reply
+	return len(buf), nil
+}
+
+func TestCircuitBreakerTransportRefusesConcurrentUse(t *testing.T) {
+	server := &blockingReadTransport{&flakyTransport{TMemoryBuffer: NewTMemoryBuffer()}, make(chan bool), make(chan bool)}
+	trans := NewTCircuitBreakerTransport(server, newTestCircuitBreaker())
+	trans.Write([]byte{1})
+	trans.Flush()
+	read := make(chan error)
+	go func() {
+		_, err := trans.Read(make([]byte, 1))
+		read <- err
+	}()
+	<-server.reading
+	if _, err := trans.Write([]byte{1}); err == nil {
+		t.Fatalf("Expected a write during a read to be refused")
+	}
+	close(server.reply)
+	if err := <-read; err != nil {
+		t.Fatalf("Expected the read to succeed, but found %s", err)
+	}
+}
+
+func TestCircuitBreakerTransportCloseAbandonsCall(t *testing.T) {
+	server := &flakyTransport{TMemoryBuffer: NewTMemoryBuffer()}
+	breaker := newTestCircuitBreaker()
+	breaker.SetMinCalls(1)
+	trans := NewTCircuitBreakerTransport(server, breaker)
+	server.failing = true
+	callThrough(trans)
+	time.Sleep(30 * time.Millisecond)
+
+	// the probe is closed before its reply comes in
+	trans.Write([]byte{1})
+	trans.Flush()
+	trans.Close()
+	if state := breaker.State(); state != CIRCUIT_HALF_OPEN {
+		t.Fatalf("Expected a closed call not to decide the probe, but found %s", state)
+	}
+}
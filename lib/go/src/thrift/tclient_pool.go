@@ -0,0 +1,378 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_CLIENT_POOL_MAX_OPEN = 0
+	DEFAULT_CLIENT_POOL_MAX_IDLE = 2
+)
+
+/**
+ * Builds a client on top of a pooled transport, usually by calling a
+ * generated New<Service>ClientFactory:
+ *
+ *   func(t thrift.TTransport, f thrift.TProtocolFactory) interface{} {
+ *     return tutorial.NewCalculatorClientFactory(t, f)
+ *   }
+ */
+type TClientFactory func(transport TTransport, protocolFactory TProtocolFactory) interface{}
+
+/**
+ * A client handed out by a TClientPool.  Client is what the TClientFactory
+ * of the pool built; it must be handed back with Put once the caller is
+ * done with it.
+ */
+type TPooledClient struct {
+	Client    interface{}
+	transport TTransport
+	created   time.Time
+	idleSince time.Time
+	// handed out and not yet handed back
+	inUse bool
+}
+
+func (p *TPooledClient) Transport() TTransport {
+	return p.transport
+}
+
+/**
+ * Pool of clients connected to the same service, for sharing a bounded
+ * number of connections between goroutines.  Idle clients are checked with
+ * IsOpen and Peek before being handed out again, and clients that failed
+ * with a transport or protocol exception are closed rather than reused.
+ * Over a TSocket, Peek finds the connections the server hung up on while
+ * they were idle, but only on Unix; elsewhere such a connection is only
+ * found out by the call that fails on it.
+ */
+type TClientPool struct {
+	newTransport     func() TTransport
+	transportFactory TTransportFactory
+	protocolFactory  TProtocolFactory
+	clientFactory    TClientFactory
+
+	lock        sync.Mutex
+	released    *sync.Cond
+	idle        []*TPooledClient
+	open        int
+	closed      bool
+	maxOpen     int
+	maxIdle     int
+	maxIdleTime time.Duration
+	maxLifetime time.Duration
+}
+
+/**
+ * Creates a pool of clients connected to addr through a TSocket with the
+ * given timeout, wrapped by transportFactory.
+ */
+func NewTClientPool(addr net.Addr, nsecTimeout int64, transportFactory TTransportFactory, protocolFactory TProtocolFactory, clientFactory TClientFactory) *TClientPool {
+	return NewTClientPoolFunc(func() TTransport { return NewTSocket(addr, nsecTimeout) }, transportFactory, protocolFactory, clientFactory)
+}
+
+/**
+ * Creates a pool of clients whose connections are made by opening the
+ * transports returned by newTransport, wrapped by transportFactory.
+ */
+func NewTClientPoolFunc(newTransport func() TTransport, transportFactory TTransportFactory, protocolFactory TProtocolFactory, clientFactory TClientFactory) *TClientPool {
+	p := &TClientPool{
+		newTransport:     newTransport,
+		transportFactory: transportFactory,
+		protocolFactory:  protocolFactory,
+		clientFactory:    clientFactory,
+		maxOpen:          DEFAULT_CLIENT_POOL_MAX_OPEN,
+		maxIdle:          DEFAULT_CLIENT_POOL_MAX_IDLE,
+	}
+	p.released = sync.NewCond(&p.lock)
+	return p
+}
+
+func (p *TClientPool) MaxOpen() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.maxOpen
+}
+
+/**
+ * Sets the most clients, idle or in use, the pool keeps open at once; Get
+ * waits for a client to be handed back when there are that many.  Zero
+ * means no limit.
+ */
+func (p *TClientPool) SetMaxOpen(maxOpen int) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.maxOpen = maxOpen
+	p.released.Broadcast()
+}
+
+func (p *TClientPool) MaxIdle() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.maxIdle
+}
+
+/**
+ * Sets the most idle clients the pool keeps; clients handed back beyond
+ * that are closed.
+ */
+func (p *TClientPool) SetMaxIdle(maxIdle int) {
+	p.lock.Lock()
+	p.maxIdle = maxIdle
+	closing := p.trimLocked(time.Now())
+	p.lock.Unlock()
+	closeTransports(closing)
+}
+
+func (p *TClientPool) MaxIdleTime() time.Duration {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.maxIdleTime
+}
+
+/**
+ * Sets how long a client may sit idle before it is closed instead of being
+ * handed out.  Zero means no limit.
+ */
+func (p *TClientPool) SetMaxIdleTime(maxIdleTime time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.maxIdleTime = maxIdleTime
+}
+
+func (p *TClientPool) MaxLifetime() time.Duration {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.maxLifetime
+}
+
+/**
+ * Sets how long after being opened a client is closed, once it is next
+ * handed back or found idle.  Zero means no limit.
+ */
+func (p *TClientPool) SetMaxLifetime(maxLifetime time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.maxLifetime = maxLifetime
+}
+
+/**
+ * Returns the number of clients open, idle or in use.
+ */
+func (p *TClientPool) NumOpen() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.open
+}
+
+/**
+ * Returns the number of idle clients.
+ */
+func (p *TClientPool) NumIdle() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return len(p.idle)
+}
+
+/**
+ * Hands out an idle client that passes the health checks, or opens a new
+ * one.  Waits while the pool has MaxOpen clients in use.
+ */
+func (p *TClientPool) Get() (*TPooledClient, error) {
+	var closing []TTransport
+	p.lock.Lock()
+	for {
+		if p.closed {
+			p.lock.Unlock()
+			closeTransports(closing)
+			return nil, NewTTransportException(NOT_OPEN, "Client pool closed")
+		}
+		now := time.Now()
+		for len(p.idle) > 0 {
+			client := p.idle[len(p.idle)-1]
+			p.idle = p.idle[:len(p.idle)-1]
+			if p.expiredLocked(client, now) || !client.transport.IsOpen() || !client.transport.Peek() {
+				closing = append(closing, p.dropLocked(client))
+				continue
+			}
+			client.inUse = true
+			p.lock.Unlock()
+			closeTransports(closing)
+			return client, nil
+		}
+		if p.maxOpen <= 0 || p.open < p.maxOpen {
+			break
+		}
+		if len(closing) > 0 {
+			// not while waiting, which may take long
+			p.lock.Unlock()
+			closeTransports(closing)
+			closing = nil
+			p.lock.Lock()
+			continue
+		}
+		p.released.Wait()
+	}
+	p.open++
+	p.lock.Unlock()
+	closeTransports(closing)
+
+	transport := p.transportFactory.GetTransport(p.newTransport())
+	if err := transport.Open(); err != nil {
+		p.lock.Lock()
+		p.open--
+		p.released.Signal()
+		p.lock.Unlock()
+		return nil, err
+	}
+	return &TPooledClient{
+		Client:    p.clientFactory(transport, p.protocolFactory),
+		transport: transport,
+		created:   time.Now(),
+		inUse:     true,
+	}, nil
+}
+
+/**
+ * Hands client back to the pool.  err is the outcome of the last call made
+ * with it: after a transport or protocol exception the connection may be
+ * broken or in the middle of a message, so the client is closed.  Errors
+ * the service returned, including TApplicationException, leave the
+ * connection usable.  Panics if client is not in use, as handing it back
+ * twice would let two callers share its connection.
+ */
+func (p *TClientPool) Put(client *TPooledClient, err error) {
+	p.lock.Lock()
+	if !client.inUse {
+		p.lock.Unlock()
+		panic("Client handed back to the pool while not in use")
+	}
+	client.inUse = false
+	now := time.Now()
+	var closing []TTransport
+	if p.closed || isConnectionError(err) || p.expiredLocked(client, now) || !client.transport.IsOpen() {
+		closing = append(closing, p.dropLocked(client))
+	} else {
+		client.idleSince = now
+		p.idle = append(p.idle, client)
+		closing = p.trimLocked(now)
+		p.released.Signal()
+	}
+	p.lock.Unlock()
+	closeTransports(closing)
+}
+
+/**
+ * Gets a client, calls f with it and hands it back with the error f
+ * returned, which is returned in turn.
+ */
+func (p *TClientPool) Do(f func(client interface{}) error) error {
+	client, err := p.Get()
+	if err != nil {
+		return err
+	}
+	err = f(client.Client)
+	p.Put(client, err)
+	return err
+}
+
+/**
+ * Closes the idle clients and stops handing out new ones.  Clients in use
+ * are closed when they are handed back.
+ */
+func (p *TClientPool) Close() error {
+	p.lock.Lock()
+	p.closed = true
+	var closing []TTransport
+	for _, client := range p.idle {
+		closing = append(closing, p.dropLocked(client))
+	}
+	p.idle = nil
+	p.released.Broadcast()
+	p.lock.Unlock()
+	closeTransports(closing)
+	return nil
+}
+
+func (p *TClientPool) expiredLocked(client *TPooledClient, now time.Time) bool {
+	if p.maxLifetime > 0 && now.Sub(client.created) >= p.maxLifetime {
+		return true
+	}
+	return p.maxIdleTime > 0 && !client.idleSince.IsZero() && now.Sub(client.idleSince) >= p.maxIdleTime
+}
+
+/**
+ * Drops the idle clients that have expired, then the oldest ones beyond
+ * maxIdle, returning their transports to be closed.
+ */
+func (p *TClientPool) trimLocked(now time.Time) []TTransport {
+	var closing []TTransport
+	kept := p.idle[:0]
+	for _, client := range p.idle {
+		if p.expiredLocked(client, now) {
+			closing = append(closing, p.dropLocked(client))
+		} else {
+			kept = append(kept, client)
+		}
+	}
+	for i := len(kept); i < len(p.idle); i++ {
+		p.idle[i] = nil
+	}
+	p.idle = kept
+	for len(p.idle) > p.maxIdle && len(p.idle) > 0 {
+		closing = append(closing, p.dropLocked(p.idle[0]))
+		p.idle[0] = nil
+		p.idle = p.idle[1:]
+	}
+	return closing
+}
+
+/**
+ * Stops counting client as open, returning its transport for the caller to
+ * close once the lock is released, as closing may block.
+ */
+func (p *TClientPool) dropLocked(client *TPooledClient) TTransport {
+	p.open--
+	p.released.Signal()
+	return client.transport
+}
+
+func closeTransports(transports []TTransport) {
+	for _, transport := range transports {
+		transport.Close()
+	}
+}
+
+/**
+ * Whether err leaves the connection it came from in an unknown state.
+ */
+func isConnectionError(err error) bool {
+	switch err.(type) {
+	case nil:
+		return false
+	case TTransportException, TProtocolException:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,229 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func newTestClientPool(addr net.Addr) *TClientPool {
+	return NewTClientPool(addr, 5e9, NewTTransportFactory(), NewTBinaryProtocolFactoryDefault(),
+		func(t TTransport, f TProtocolFactory) interface{} { return f.GetProtocol(t) })
+}
+
+func TestClientPoolReusesClients(t *testing.T) {
+	server, addr := startTestServer(t)
+	defer server.Stop()
+	pool := newTestClientPool(addr)
+	defer pool.Close()
+
+	first, err := pool.Get()
+	if err != nil {
+		t.Fatalf("Unable to get a client: %s", err)
+	}
+	pool.Put(first, pingCall(first.Client.(TProtocol)))
+	second, err := pool.Get()
+	if err != nil {
+		t.Fatalf("Unable to get a client: %s", err)
+	}
+	if second != first {
+		t.Fatalf("Expected the idle client to be reused")
+	}
+	pool.Put(second, pingCall(second.Client.(TProtocol)))
+	if pool.NumOpen() != 1 || pool.NumIdle() != 1 {
+		t.Fatalf("Expected 1 open idle client, but found %d open %d idle", pool.NumOpen(), pool.NumIdle())
+	}
+}
+
+func TestClientPoolEvictsBrokenClients(t *testing.T) {
+	server, addr := startTestServer(t)
+	defer server.Stop()
+	pool := newTestClientPool(addr)
+	defer pool.Close()
+
+	failed, _ := pool.Get()
+	pool.Put(failed, NewTTransportException(TIMED_OUT, "timed out"))
+	if failed.Transport().IsOpen() || pool.NumOpen() != 0 {
+		t.Fatalf("Expected a client failing with a transport exception to be closed")
+	}
+
+	kept, _ := pool.Get()
+	pool.Put(kept, NewTApplicationException(INTERNAL_ERROR, "handler failed"))
+	if pool.NumIdle() != 1 {
+		t.Fatalf("Expected a client failing with an application exception to be kept")
+	}
+	kept.Transport().Close()
+	client, err := pool.Get()
+	if err != nil {
+		t.Fatalf("Unable to get a client: %s", err)
+	}
+	if client == kept {
+		t.Fatalf("Expected a closed idle client not to be handed out")
+	}
+	if err := pingCall(client.Client.(TProtocol)); err != nil {
+		t.Fatalf("Expected a working client, but found %s", err)
+	}
+	pool.Put(client, nil)
+}
+
+func TestClientPoolDropsClientsTheServerHungUpOn(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen: %s", err)
+	}
+	defer listener.Close()
+	pool := newTestClientPool(listener.Addr())
+	defer pool.Close()
+
+	hungUp, err := pool.Get()
+	if err != nil {
+		t.Fatalf("Unable to get a client: %s", err)
+	}
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("Unable to accept: %s", err)
+	}
+	if !hungUp.Transport().Peek() {
+		t.Fatalf("Expected Peek to find the connection up")
+	}
+	pool.Put(hungUp, nil)
+	conn.Close()
+	for deadline := time.Now().Add(time.Second); hungUp.Transport().Peek(); {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected Peek to find that the server hung up")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	client, err := pool.Get()
+	if err != nil {
+		t.Fatalf("Unable to get a client: %s", err)
+	}
+	if client == hungUp || hungUp.Transport().IsOpen() {
+		t.Fatalf("Expected the client the server hung up on to be closed rather than handed out")
+	}
+	pool.Put(client, nil)
+}
+
+func TestClientPoolLimits(t *testing.T) {
+	server, addr := startTestServer(t)
+	defer server.Stop()
+	pool := newTestClientPool(addr)
+	defer pool.Close()
+	pool.SetMaxIdle(1)
+
+	first, _ := pool.Get()
+	second, _ := pool.Get()
+	pool.Put(first, nil)
+	pool.Put(second, nil)
+	if pool.NumOpen() != 1 || first.Transport().IsOpen() {
+		t.Fatalf("Expected the oldest idle client beyond MaxIdle to be closed")
+	}
+
+	pool.SetMaxLifetime(time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	client, _ := pool.Get()
+	if client == second || second.Transport().IsOpen() {
+		t.Fatalf("Expected a client past MaxLifetime to be closed")
+	}
+	pool.SetMaxLifetime(0)
+
+	pool.SetMaxOpen(1)
+	got := make(chan *TPooledClient)
+	go func() {
+		waiting, _ := pool.Get()
+		got <- waiting
+	}()
+	select {
+	case <-got:
+		t.Fatalf("Expected Get to wait while MaxOpen clients are in use")
+	case <-time.After(50 * time.Millisecond):
+	}
+	pool.Put(client, nil)
+	if waiting := <-got; waiting != client {
+		t.Fatalf("Expected the waiting Get to receive the client handed back")
+	}
+}
+
+func TestClientPoolRefusesDoublePut(t *testing.T) {
+	server, addr := startTestServer(t)
+	defer server.Stop()
+	pool := newTestClientPool(addr)
+	defer pool.Close()
+
+	client, err := pool.Get()
+	if err != nil {
+		t.Fatalf("Unable to get a client: %s", err)
+	}
+	pool.Put(client, nil)
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("Expected handing a client back twice to panic")
+		}
+		if pool.NumIdle() != 1 || pool.NumOpen() != 1 {
+			t.Fatalf("Expected the client to be idle once, but found %d idle and %d open", pool.NumIdle(), pool.NumOpen())
+		}
+	}()
+	pool.Put(client, nil)
+}
+
+/**
+ * Transport whose Close waits until release is closed.
+ */
+type slowClosingTransport struct {
+	*TMemoryBuffer
+	release chan bool
+}
+
+func (p *slowClosingTransport) Close() error {
+	<-p.release
+	return nil
+}
+
+func TestClientPoolClosesOutsideTheLock(t *testing.T) {
+	release := make(chan bool)
+	pool := NewTClientPoolFunc(func() TTransport { return &slowClosingTransport{NewTMemoryBuffer(), release} },
+		NewTTransportFactory(), NewTBinaryProtocolFactoryDefault(),
+		func(t TTransport, f TProtocolFactory) interface{} { return f.GetProtocol(t) })
+	client, err := pool.Get()
+	if err != nil {
+		t.Fatalf("Unable to get a client: %s", err)
+	}
+	closed := make(chan bool)
+	go func() {
+		pool.Put(client, NewTTransportException(TIMED_OUT, "timed out"))
+		close(closed)
+	}()
+	dropped := make(chan bool)
+	go func() {
+		for pool.NumOpen() != 0 {
+			time.Sleep(time.Millisecond)
+		}
+		close(dropped)
+	}()
+	select {
+	case <-dropped:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the pool to stay usable while a transport is being closed")
+	}
+	close(release)
+	<-closed
+}
@@ -0,0 +1,85 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"context"
+	"time"
+)
+
+/**
+ * How long generated clients wait for a call, from sending the request to
+ * reading the reply.  Methods holds the timeouts of particular methods, by
+ * their name in the IDL, and Default that of every other method.  A zero
+ * timeout means no limit beyond that of the transport.
+ */
+type TClientTimeouts struct {
+	Default time.Duration
+	Methods map[string]time.Duration
+}
+
+func (p TClientTimeouts) Timeout(method string) time.Duration {
+	if timeout, ok := p.Methods[method]; ok {
+		return timeout
+	}
+	return p.Default
+}
+
+/**
+ * Returns the error of a call that ran out of time.
+ */
+func NewTTransportExceptionTimedOut(timeout time.Duration) TTransportException {
+	return NewTTransportException(TIMED_OUT, "Call timed out after "+timeout.String())
+}
+
+/**
+ * Bounds a call made over transport by timeout, if positive, as
+ * WatchContext does for a context.  A call cut short by the timeout fails
+ * with a TIMED_OUT TTransportException, and the transport is closed so
+ * that the late reply cannot be read as the reply to the next call; open
+ * it again to carry on.
+ */
+func WatchTimeout(timeout time.Duration, transport TTransport) func(err error) error {
+	return WatchContextTimeout(context.Background(), timeout, transport)
+}
+
+/**
+ * Bounds a call made over transport by both ctx and timeout.  Whichever
+ * ends first determines the error: ctx.Err() for ctx, a TIMED_OUT
+ * TTransportException for the timeout.
+ */
+func WatchContextTimeout(ctx context.Context, timeout time.Duration, transport TTransport) func(err error) error {
+	if timeout <= 0 {
+		return WatchContext(ctx, transport)
+	}
+	if deadline, ok := ctx.Deadline(); ok && !deadline.After(time.Now().Add(timeout)) {
+		return WatchContext(ctx, transport)
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+	done := WatchContext(timeoutCtx, transport)
+	return func(err error) error {
+		err = done(err)
+		cancel()
+		if err == context.DeadlineExceeded {
+			return NewTTransportExceptionTimedOut(timeout)
+		}
+		return err
+	}
+}
@@ -0,0 +1,111 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClientTimeoutsByMethod(t *testing.T) {
+	timeouts := TClientTimeouts{Default: time.Second, Methods: map[string]time.Duration{"slow": time.Minute}}
+	if timeout := timeouts.Timeout("slow"); timeout != time.Minute {
+		t.Fatalf("Expected the timeout of slow to be 1m, but found %s", timeout)
+	}
+	if timeout := timeouts.Timeout("ping"); timeout != time.Second {
+		t.Fatalf("Expected the default timeout of 1s, but found %s", timeout)
+	}
+	if timeout := (TClientTimeouts{}).Timeout("ping"); timeout != 0 {
+		t.Fatalf("Expected no timeout, but found %s", timeout)
+	}
+}
+
+func TestWatchTimeout(t *testing.T) {
+	serverTransport, addr := listenForTest(t)
+	defer serverTransport.Close()
+	go serverTransport.Accept()
+
+	trans, prot := openTestClient(t, addr)
+	done := WatchTimeout(50*time.Millisecond, trans)
+	writeEmptyCall(t, prot, "slow", 1)
+	_, _, _, readErr := prot.ReadMessageBegin()
+	err := done(readErr)
+	if e, ok := err.(TTransportException); !ok || e.TypeId() != TIMED_OUT {
+		t.Fatalf("Expected TIMED_OUT, but found %v", err)
+	}
+	if trans.IsOpen() {
+		t.Fatalf("Expected the transport to be closed after a timeout")
+	}
+}
+
+func TestWatchContextTimeoutEarlierContext(t *testing.T) {
+	serverTransport, addr := listenForTest(t)
+	defer serverTransport.Close()
+	go serverTransport.Accept()
+
+	trans, prot := openTestClient(t, addr)
+	defer trans.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	done := WatchContextTimeout(ctx, time.Minute, trans)
+	_, _, _, err := prot.ReadMessageBegin()
+	if err := done(err); err != context.DeadlineExceeded {
+		t.Fatalf("Expected context.DeadlineExceeded, but found %v", err)
+	}
+}
+
+func TestWatchTimeoutHttpClient(t *testing.T) {
+	release := make(chan bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-time.After(200 * time.Millisecond):
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	trans, err := NewTHttpClient(server.URL)
+	if err != nil {
+		t.Fatalf("Unable to create HTTP client: %s", err)
+	}
+	prot := NewTBinaryProtocolTransport(trans)
+	start := time.Now()
+	done := WatchTimeout(20*time.Millisecond, trans)
+	prot.WriteMessageBegin("slow", CALL, 1)
+	prot.WriteStructBegin("args")
+	prot.WriteFieldStop()
+	prot.WriteStructEnd()
+	prot.WriteMessageEnd()
+	callErr := prot.Flush()
+	if callErr == nil {
+		_, _, _, callErr = prot.ReadMessageBegin()
+	}
+	err = done(callErr)
+	if e, ok := err.(TTransportException); !ok || e.TypeId() != TIMED_OUT {
+		t.Fatalf("Expected TIMED_OUT, but found %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Fatalf("Expected the timeout to cut the request short, but it took %s", elapsed)
+	}
+}
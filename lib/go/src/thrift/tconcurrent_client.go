@@ -0,0 +1,418 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+/**
+ * The generated <method>Args struct of a call.
+ */
+type TClientArgs interface {
+	Write(oprot TProtocol) TProtocolException
+}
+
+/**
+ * The generated <method>Result struct of a call.
+ */
+type TClientResult interface {
+	Read(iprot TProtocol) TProtocolException
+}
+
+/**
+ * A call issued through a TConcurrentClient.  Once the reply has been read
+ * into Result, or the call has failed with Error, the call is sent on Done.
+ */
+type TClientCall struct {
+	Method   string
+	SeqId    int32
+	Args     TClientArgs
+	Result   TClientResult
+	Error    error
+	Done     chan *TClientCall
+	finished chan bool
+	timer    *time.Timer
+}
+
+func newTClientCall(method string, args TClientArgs, result TClientResult, done chan *TClientCall) *TClientCall {
+	if done == nil {
+		done = make(chan *TClientCall, 1)
+	}
+	return &TClientCall{Method: method, Args: args, Result: result, Done: done, finished: make(chan bool)}
+}
+
+/**
+ * Returns a channel that is closed once the call has completed, for waiting
+ * on several calls at once.
+ */
+func (p *TClientCall) Finished() <-chan bool {
+	return p.finished
+}
+
+/**
+ * Waits for the call to complete and returns its error.  Unlike receiving
+ * from Done, Wait may be called any number of times.
+ */
+func (p *TClientCall) Wait() error {
+	<-p.finished
+	return p.Error
+}
+
+func (p *TClientCall) done() {
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+	close(p.finished)
+	select {
+	case p.Done <- p:
+	default:
+		// Done has no room; the caller asked not to block the reader
+	}
+}
+
+/**
+ * Client side of a connection that can be shared between goroutines.
+ * Requests are written one at a time, each with its own seqid, and a reader
+ * goroutine hands every reply to the call waiting on that seqid, so replies
+ * may come back in any order.
+ *
+ * Once writing a request or reading a reply fails, every pending and later
+ * call fails with that error; create a new client on a new transport to
+ * carry on.  Calls are never retried, as there is no connection left to
+ * retry them on.
+ */
+type TConcurrentClient struct {
+	transport      TTransport
+	inputProtocol  TProtocol
+	outputProtocol TProtocol
+
+	writeLock sync.Mutex
+
+	lock     sync.Mutex
+	seqId    int32
+	pending  map[int32]*TClientCall
+	reading  bool
+	closed   bool
+	err      error
+	timeouts TClientTimeouts
+}
+
+func NewTConcurrentClientFactory(t TTransport, f TProtocolFactory) *TConcurrentClient {
+	return NewTConcurrentClientProtocol(t, f.GetProtocol(t), f.GetProtocol(t))
+}
+
+func NewTConcurrentClientProtocol(t TTransport, iprot TProtocol, oprot TProtocol) *TConcurrentClient {
+	return &TConcurrentClient{
+		transport:      t,
+		inputProtocol:  iprot,
+		outputProtocol: oprot,
+		pending:        make(map[int32]*TClientCall),
+	}
+}
+
+func (p *TConcurrentClient) Transport() TTransport {
+	return p.transport
+}
+
+func (p *TConcurrentClient) Timeouts() TClientTimeouts {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.timeouts
+}
+
+/**
+ * Sets how long calls wait for their reply before failing with a TIMED_OUT
+ * TTransportException.  The clock starts once the request is queued for
+ * writing; the late reply is discarded, so the connection stays usable.
+ * Calls already sent keep their timeout.
+ */
+func (p *TConcurrentClient) SetTimeouts(timeouts TClientTimeouts) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.timeouts = timeouts
+}
+
+/**
+ * Sends a request for method and waits for the reply to be read into result.
+ */
+func (p *TConcurrentClient) Call(method string, args TClientArgs, result TClientResult) error {
+	return p.Go(method, args, result, nil).Wait()
+}
+
+/**
+ * Sends a request for method without waiting for the reply.  The call is
+ * sent on done, which is allocated if nil, once it has completed; done must
+ * have room for the call or the reply is dropped.
+ */
+func (p *TConcurrentClient) Go(method string, args TClientArgs, result TClientResult, done chan *TClientCall) *TClientCall {
+	call := newTClientCall(method, args, result, done)
+	p.send(call, CALL)
+	return call
+}
+
+/**
+ * Sends a oneway request for method.
+ */
+func (p *TConcurrentClient) Oneway(method string, args TClientArgs) error {
+	call := newTClientCall(method, args, nil, nil)
+	p.send(call, ONEWAY)
+	return call.Error
+}
+
+/**
+ * Like Call, but gives up on the reply once ctx is done, returning ctx.Err().
+ */
+func (p *TConcurrentClient) CallContext(ctx context.Context, method string, args TClientArgs, result TClientResult) error {
+	return p.GoContext(ctx, method, args, result, nil).Wait()
+}
+
+/**
+ * Like Go, but the call is abandoned with ctx.Err() if ctx is done before
+ * the reply has been read.  A request that has been sent is not recalled;
+ * its reply is discarded.
+ */
+func (p *TConcurrentClient) GoContext(ctx context.Context, method string, args TClientArgs, result TClientResult, done chan *TClientCall) *TClientCall {
+	if err := ctx.Err(); err != nil {
+		call := newTClientCall(method, args, result, done)
+		call.Error = err
+		call.done()
+		return call
+	}
+	call := p.Go(method, args, result, done)
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-call.finished:
+			case <-ctx.Done():
+				p.Abandon(call, ctx.Err())
+			}
+		}()
+	}
+	return call
+}
+
+/**
+ * Like Oneway, but does not send the request if ctx is already done.
+ */
+func (p *TConcurrentClient) OnewayContext(ctx context.Context, method string, args TClientArgs) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return p.Oneway(method, args)
+}
+
+/**
+ * Gives up on call; a reply arriving for it later is discarded.  Returns
+ * false if the call has already completed.
+ */
+func (p *TConcurrentClient) Abandon(call *TClientCall, err error) bool {
+	p.lock.Lock()
+	if p.pending[call.SeqId] != call {
+		p.lock.Unlock()
+		return false
+	}
+	delete(p.pending, call.SeqId)
+	p.lock.Unlock()
+	call.Error = err
+	call.done()
+	return true
+}
+
+/**
+ * Closes the transport, failing any calls still waiting for a reply.
+ */
+func (p *TConcurrentClient) Close() error {
+	p.writeLock.Lock()
+	defer p.writeLock.Unlock()
+	p.lock.Lock()
+	p.closed = true
+	if p.err == nil {
+		p.err = NewTTransportException(NOT_OPEN, "Client closed")
+	}
+	reading := p.reading
+	p.lock.Unlock()
+	if reading {
+		// the reader owns the transport until it has woken up and failed
+		interruptTransport(p.transport)
+		return nil
+	}
+	return p.transport.Close()
+}
+
+func (p *TConcurrentClient) send(call *TClientCall, typeId TMessageType) {
+	p.writeLock.Lock()
+	defer p.writeLock.Unlock()
+
+	p.lock.Lock()
+	if p.err != nil {
+		p.lock.Unlock()
+		call.Error = p.err
+		call.done()
+		return
+	}
+	p.seqId++
+	call.SeqId = p.seqId
+	if typeId == CALL {
+		// registered before writing, the reply may beat us back
+		p.pending[call.SeqId] = call
+		if timeout := p.timeouts.Timeout(call.Method); timeout > 0 {
+			call.timer = time.AfterFunc(timeout, func() {
+				p.Abandon(call, NewTTransportExceptionTimedOut(timeout))
+			})
+		}
+		if !p.reading {
+			p.reading = true
+			go p.readReplies()
+		}
+	}
+	p.lock.Unlock()
+
+	if err := p.write(call, typeId); err != nil {
+		if typeId == CALL {
+			p.Abandon(call, err)
+		} else {
+			call.Error = err
+			call.done()
+		}
+		p.writeFailed(err)
+		return
+	}
+	if typeId == ONEWAY {
+		call.done()
+	}
+}
+
+func (p *TConcurrentClient) write(call *TClientCall, typeId TMessageType) error {
+	oprot := p.outputProtocol
+	if err := oprot.WriteMessageBegin(call.Method, typeId, call.SeqId); err != nil {
+		return err
+	}
+	if err := call.Args.Write(oprot); err != nil {
+		return err
+	}
+	if err := oprot.WriteMessageEnd(); err != nil {
+		return err
+	}
+	return oprot.Flush()
+}
+
+func (p *TConcurrentClient) readReplies() {
+	iprot := p.inputProtocol
+	for {
+		name, typeId, seqId, err := iprot.ReadMessageBegin()
+		if err != nil {
+			p.fail(err)
+			return
+		}
+		p.lock.Lock()
+		call := p.pending[seqId]
+		delete(p.pending, seqId)
+		p.lock.Unlock()
+		if call == nil {
+			// reply to an abandoned call
+			iprot.Skip(STRUCT)
+			iprot.ReadMessageEnd()
+			continue
+		}
+		var e error
+		switch {
+		case typeId == EXCEPTION:
+			x := NewTApplicationExceptionDefault()
+			if call.Error, e = x.Read(iprot); e != nil {
+				call.Error = e
+			}
+		case name != call.Method:
+			iprot.Skip(STRUCT)
+			call.Error = NewTApplicationException(WRONG_METHOD_NAME, call.Method+" failed: wrong method name "+name)
+		default:
+			if err := call.Result.Read(iprot); err != nil {
+				e = err
+				call.Error = err
+			}
+		}
+		if err := iprot.ReadMessageEnd(); err != nil && e == nil {
+			e = err
+			call.Error = err
+		}
+		call.done()
+		if e != nil {
+			p.fail(e)
+			return
+		}
+	}
+}
+
+/**
+ * Marks the client broken once a request could not be written: what is
+ * left of it may still be buffered and would go out ahead of the next
+ * request.  The reader is woken up to fail the pending calls.
+ */
+func (p *TConcurrentClient) writeFailed(err error) {
+	p.lock.Lock()
+	if p.err == nil {
+		p.err = err
+	}
+	reading := p.reading
+	p.lock.Unlock()
+	if reading {
+		interruptTransport(p.transport)
+	}
+}
+
+/**
+ * Fails every pending call and marks the client broken.  Called by the
+ * reader as it exits.
+ */
+func (p *TConcurrentClient) fail(err error) {
+	p.lock.Lock()
+	if p.err == nil {
+		p.err = err
+	}
+	err = p.err
+	pending := p.pending
+	p.pending = make(map[int32]*TClientCall)
+	p.reading = false
+	closed := p.closed
+	p.lock.Unlock()
+	for _, call := range pending {
+		call.Error = err
+		call.done()
+	}
+	if closed {
+		p.transport.Close()
+	}
+}
+
+/**
+ * Unblocks a pending Read on t from another goroutine, closing t if it
+ * cannot be interrupted.
+ */
+func interruptTransport(t TTransport) {
+	if i, ok := t.(interface {
+		Interrupt() error
+	}); ok {
+		i.Interrupt()
+	} else {
+		t.Close()
+	}
+}
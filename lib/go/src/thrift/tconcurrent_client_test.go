@@ -0,0 +1,282 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+/**
+ * Stands in for generated args and result structs: a struct holding a
+ * single i32 field.
+ */
+type int32Struct struct {
+	value int32
+}
+
+func (p *int32Struct) Write(oprot TProtocol) TProtocolException {
+	oprot.WriteStructBegin("int32Struct")
+	oprot.WriteFieldBegin("value", I32, 1)
+	oprot.WriteI32(p.value)
+	oprot.WriteFieldEnd()
+	oprot.WriteFieldStop()
+	return oprot.WriteStructEnd()
+}
+
+func (p *int32Struct) Read(iprot TProtocol) TProtocolException {
+	iprot.ReadStructBegin()
+	for {
+		_, fieldType, fieldId, err := iprot.ReadFieldBegin()
+		if err != nil {
+			return err
+		}
+		if fieldType == STOP {
+			break
+		}
+		if fieldId == 1 && fieldType == I32 {
+			if p.value, err = iprot.ReadI32(); err != nil {
+				return err
+			}
+		} else {
+			iprot.Skip(fieldType)
+		}
+		iprot.ReadFieldEnd()
+	}
+	return iprot.ReadStructEnd()
+}
+
+type testRequest struct {
+	name  string
+	seqId int32
+	args  int32Struct
+}
+
+func newConcurrentClientPipe(t *testing.T) (*TConcurrentClient, TProtocol) {
+	clientConn, serverConn := net.Pipe()
+	clientTrans, err := NewTSocketConn(clientConn)
+	if err != nil {
+		t.Fatalf("Unable to wrap client pipe: %s", err)
+	}
+	serverTrans, err := NewTSocketConn(serverConn)
+	if err != nil {
+		t.Fatalf("Unable to wrap server pipe: %s", err)
+	}
+	client := NewTConcurrentClientFactory(clientTrans, NewTBinaryProtocolFactoryDefault())
+	return client, NewTBinaryProtocolTransport(serverTrans)
+}
+
+func readTestRequests(t *testing.T, prot TProtocol, n int) []testRequest {
+	requests := make([]testRequest, n)
+	for i := range requests {
+		name, _, seqId, err := prot.ReadMessageBegin()
+		if err != nil {
+			t.Errorf("Unable to read request: %s", err)
+			return nil
+		}
+		requests[i].name, requests[i].seqId = name, seqId
+		requests[i].args.Read(prot)
+		prot.ReadMessageEnd()
+	}
+	return requests
+}
+
+func writeTestReply(prot TProtocol, request testRequest) {
+	prot.WriteMessageBegin(request.name, REPLY, request.seqId)
+	request.args.Write(prot)
+	prot.WriteMessageEnd()
+	prot.Flush()
+}
+
+/**
+ * Socket whose next Flush fails once failFlush is set.
+ */
+type failingFlushSocket struct {
+	*TSocket
+	failFlush bool
+}
+
+func (p *failingFlushSocket) Flush() error {
+	if p.failFlush {
+		p.failFlush = false
+		return NewTTransportException(UNKNOWN_TRANSPORT_EXCEPTION, "flush failed")
+	}
+	return p.TSocket.Flush()
+}
+
+func TestConcurrentClientBreaksOnWriteFailure(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer serverConn.Close()
+	clientSocket, err := NewTSocketConn(clientConn)
+	if err != nil {
+		t.Fatalf("Unable to wrap client pipe: %s", err)
+	}
+	serverSocket, err := NewTSocketConn(serverConn)
+	if err != nil {
+		t.Fatalf("Unable to wrap server pipe: %s", err)
+	}
+	trans := &failingFlushSocket{TSocket: clientSocket}
+	client := NewTConcurrentClientFactory(trans, NewTBinaryProtocolFactoryDefault())
+	defer client.Close()
+	server := NewTBinaryProtocolTransport(serverSocket)
+	go readTestRequests(t, server, 1)
+
+	pending := client.Go("first", &int32Struct{1}, &int32Struct{}, nil)
+	trans.failFlush = true
+	if err := client.Call("second", &int32Struct{2}, &int32Struct{}); err == nil {
+		t.Fatalf("Expected the call whose request could not be flushed to fail")
+	}
+	if err := pending.Wait(); err == nil {
+		t.Fatalf("Expected the pending call to fail once the client broke")
+	}
+	if err := client.Call("third", &int32Struct{3}, &int32Struct{}); err == nil {
+		t.Fatalf("Expected calls after a failed write to fail")
+	}
+}
+
+func TestConcurrentClientOutOfOrderReplies(t *testing.T) {
+	client, server := newConcurrentClientPipe(t)
+	defer client.Close()
+	go func() {
+		requests := readTestRequests(t, server, 3)
+		for i := len(requests) - 1; i >= 0; i-- {
+			writeTestReply(server, requests[i])
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := int32(1); i <= 3; i++ {
+		wg.Add(1)
+		go func(value int32) {
+			defer wg.Done()
+			result := &int32Struct{}
+			if err := client.Call("echo", &int32Struct{value}, result); err != nil {
+				t.Errorf("Unable to call echo: %s", err)
+			} else if result.value != value {
+				t.Errorf("Expected echo of %d, but found %d", value, result.value)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestConcurrentClientException(t *testing.T) {
+	client, server := newConcurrentClientPipe(t)
+	defer client.Close()
+	go func() {
+		requests := readTestRequests(t, server, 1)
+		server.WriteMessageBegin(requests[0].name, EXCEPTION, requests[0].seqId)
+		NewTApplicationException(INTERNAL_ERROR, "boom").Write(server)
+		server.WriteMessageEnd()
+		server.Flush()
+	}()
+	err := client.Call("echo", &int32Struct{1}, &int32Struct{})
+	if x, ok := err.(TApplicationException); !ok || x.TypeId() != INTERNAL_ERROR {
+		t.Fatalf("Expected INTERNAL_ERROR, but found %v", err)
+	}
+}
+
+func TestConcurrentClientFailsPendingCallsOnClose(t *testing.T) {
+	client, server := newConcurrentClientPipe(t)
+	go readTestRequests(t, server, 1)
+	call := client.Go("echo", &int32Struct{1}, &int32Struct{}, nil)
+	client.Close()
+	if (<-call.Done).Error == nil {
+		t.Fatalf("Expected the pending call to fail when the client is closed")
+	}
+	if err := client.Call("echo", &int32Struct{2}, &int32Struct{}); err == nil {
+		t.Fatalf("Expected calls on a broken client to fail")
+	}
+}
+
+func TestConcurrentClientGoWait(t *testing.T) {
+	client, server := newConcurrentClientPipe(t)
+	defer client.Close()
+	go func() {
+		requests := readTestRequests(t, server, 2)
+		writeTestReply(server, requests[1])
+		writeTestReply(server, requests[0])
+	}()
+	first := &int32Struct{}
+	second := &int32Struct{}
+	firstCall := client.Go("echo", &int32Struct{1}, first, nil)
+	secondCall := client.Go("echo", &int32Struct{2}, second, nil)
+	<-secondCall.Finished()
+	if err := secondCall.Wait(); err != nil || second.value != 2 {
+		t.Fatalf("Expected echo of 2, but found %d %v", second.value, err)
+	}
+	if err := firstCall.Wait(); err != nil || first.value != 1 {
+		t.Fatalf("Expected echo of 1, but found %d %v", first.value, err)
+	}
+	if err := firstCall.Wait(); err != nil {
+		t.Fatalf("Expected Wait to be repeatable, but found %v", err)
+	}
+}
+
+func TestConcurrentClientCallContext(t *testing.T) {
+	client, server := newConcurrentClientPipe(t)
+	defer client.Close()
+	abandoned := make(chan bool)
+	go func() {
+		requests := readTestRequests(t, server, 1)
+		<-abandoned
+		writeTestReply(server, requests[0])
+		requests = readTestRequests(t, server, 1)
+		writeTestReply(server, requests[0])
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := client.CallContext(ctx, "echo", &int32Struct{1}, &int32Struct{}); err != context.DeadlineExceeded {
+		t.Fatalf("Expected context.DeadlineExceeded, but found %v", err)
+	}
+	close(abandoned)
+
+	result := &int32Struct{}
+	if err := client.CallContext(context.Background(), "echo", &int32Struct{2}, result); err != nil || result.value != 2 {
+		t.Fatalf("Expected the late reply to be discarded and echo of 2, but found %d %v", result.value, err)
+	}
+}
+
+func TestConcurrentClientTimeouts(t *testing.T) {
+	client, server := newConcurrentClientPipe(t)
+	defer client.Close()
+	client.SetTimeouts(TClientTimeouts{Methods: map[string]time.Duration{"slow": 50 * time.Millisecond}})
+	timedOut := make(chan bool)
+	go func() {
+		requests := readTestRequests(t, server, 1)
+		<-timedOut
+		writeTestReply(server, requests[0])
+		requests = readTestRequests(t, server, 1)
+		writeTestReply(server, requests[0])
+	}()
+	err := client.Call("slow", &int32Struct{1}, &int32Struct{})
+	if e, ok := err.(TTransportException); !ok || e.TypeId() != TIMED_OUT {
+		t.Fatalf("Expected TIMED_OUT, but found %v", err)
+	}
+	close(timedOut)
+
+	result := &int32Struct{}
+	if err := client.Call("echo", &int32Struct{2}, result); err != nil || result.value != 2 {
+		t.Fatalf("Expected the late reply to be discarded and echo of 2, but found %d %v", result.value, err)
+	}
+}
@@ -0,0 +1,105 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+)
+
+/**
+ * Who is at the other end of a connection accepted by a server, for
+ * handlers to authorize and log callers by.  Servers of this package put it
+ * in the context of every request they serve, so handlers generated with
+ * the context option get it with ConnectionInfo(ctx), and processors and
+ * middleware with ConnectionInfo(RequestContext(in)).
+ */
+type TConnectionInfo struct {
+	conn net.Conn
+}
+
+type tConnectionInfoKey struct{}
+
+/**
+ * Implemented by transports over a net.Conn, such as the TSockets handed
+ * out by TServerSocket and TSSLServerSocket.
+ */
+type tConnTransport interface {
+	Conn() net.Conn
+}
+
+func NewTConnectionInfo(conn net.Conn) *TConnectionInfo {
+	return &TConnectionInfo{conn: conn}
+}
+
+/**
+ * Returns ctx carrying info, as servers do for the requests they serve.
+ */
+func WithConnectionInfo(ctx context.Context, info *TConnectionInfo) context.Context {
+	return context.WithValue(ctx, tConnectionInfoKey{}, info)
+}
+
+/**
+ * Returns the connection a request came in on, nil if ctx is not the
+ * context of a request served over a connection.
+ */
+func ConnectionInfo(ctx context.Context) *TConnectionInfo {
+	info, _ := ctx.Value(tConnectionInfoKey{}).(*TConnectionInfo)
+	return info
+}
+
+func (p *TConnectionInfo) RemoteAddr() net.Addr {
+	return p.conn.RemoteAddr()
+}
+
+func (p *TConnectionInfo) LocalAddr() net.Addr {
+	return p.conn.LocalAddr()
+}
+
+/**
+ * Returns the state of the TLS connection, nil if the connection does not
+ * use TLS or its handshake has not completed.  When the server verifies
+ * client certificates, the certificates of the caller and the chains they
+ * were verified with are in PeerCertificates and VerifiedChains.
+ */
+func (p *TConnectionInfo) TLS() *tls.ConnectionState {
+	conn, ok := p.conn.(*tls.Conn)
+	if !ok {
+		return nil
+	}
+	state := conn.ConnectionState()
+	if !state.HandshakeComplete {
+		return nil
+	}
+	return &state
+}
+
+/**
+ * Returns a context carrying the connection info of client, if it is a
+ * transport over a net.Conn.
+ */
+func connectionContext(client TTransport) context.Context {
+	ctx := context.Background()
+	if t, ok := client.(tConnTransport); ok && t.Conn() != nil {
+		ctx = WithConnectionInfo(ctx, NewTConnectionInfo(t.Conn()))
+	}
+	return ctx
+}
@@ -0,0 +1,93 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"context"
+	"crypto/tls"
+	"testing"
+)
+
+func TestConnectionInfoOfPlainConnection(t *testing.T) {
+	serverTransport, addr := listenForTest(t)
+	processor := &contextProcessor{contexts: make(chan context.Context, 1)}
+	server := NewTNonblockingServer2(processor, serverTransport)
+	go server.Serve()
+	defer server.Stop()
+
+	trans, prot := openTestClient(t, addr)
+	defer trans.Close()
+	writeEmptyCall(t, prot, "ping", 1)
+	info := ConnectionInfo(<-processor.contexts)
+	if info == nil {
+		t.Fatalf("Expected the request context to carry the connection info")
+	}
+	if info.RemoteAddr().String() != trans.Conn().LocalAddr().String() {
+		t.Fatalf("Expected the remote address %s, but found %s", trans.Conn().LocalAddr(), info.RemoteAddr())
+	}
+	if info.LocalAddr().String() != addr.String() {
+		t.Fatalf("Expected the local address %s, but found %s", addr, info.LocalAddr())
+	}
+	if info.TLS() != nil {
+		t.Fatalf("Expected no TLS state on a plain connection")
+	}
+}
+
+func TestConnectionInfoOfTLSConnection(t *testing.T) {
+	certs := newTestCertificates(t)
+	addr, err := FindAvailableTCPServerPort(40000)
+	if err != nil {
+		t.Fatalf("Unable to find available tcp port addr: %s", err)
+	}
+	serverTransport, _ := NewTSSLServerSocketAddrTimeout(addr, &tls.Config{
+		Certificates: []tls.Certificate{certs.server},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    certs.pool,
+	}, 5e9)
+	if err := serverTransport.Listen(); err != nil {
+		t.Fatalf("Unable to listen on %s: %s", addr, err)
+	}
+	processor := &contextProcessor{contexts: make(chan context.Context, 1)}
+	server := NewTSimpleServer2(processor, serverTransport)
+	go server.Serve()
+	defer server.Stop()
+
+	trans := NewTSSLSocket(addr, &tls.Config{RootCAs: certs.pool, Certificates: []tls.Certificate{certs.client}}, 5e9)
+	if err := trans.Open(); err != nil {
+		t.Fatalf("Unable to open TLS socket: %s", err)
+	}
+	defer trans.Close()
+	if err := pingCall(NewTBinaryProtocolTransport(trans)); err != nil {
+		t.Fatalf("Unable to call over TLS: %s", err)
+	}
+	state := ConnectionInfo(<-processor.contexts).TLS()
+	if state == nil {
+		t.Fatalf("Expected the TLS state of the connection")
+	}
+	if len(state.VerifiedChains) == 0 || state.PeerCertificates[0].Subject.CommonName != "client" {
+		t.Fatalf("Expected the verified client certificate, but found %v", state.PeerCertificates)
+	}
+}
+
+func TestConnectionInfoOutsideServer(t *testing.T) {
+	if info := ConnectionInfo(context.Background()); info != nil {
+		t.Fatalf("Expected no connection info outside of a server, but found %v", info)
+	}
+}
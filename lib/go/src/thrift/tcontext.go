@@ -0,0 +1,133 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"context"
+	"time"
+)
+
+/**
+ * A transport whose reads and writes can be bounded by an absolute
+ * deadline.  SetDeadline must be safe to call while another goroutine is
+ * blocked on the transport; a zero time clears the deadline.  Transports
+ * wrapping another one return an error if the one underneath cannot take a
+ * deadline.
+ */
+type TDeadlineTransport interface {
+	TTransport
+	SetDeadline(deadline time.Time) error
+}
+
+func setTransportDeadline(transport TTransport, deadline time.Time) error {
+	if t, ok := transport.(TDeadlineTransport); ok {
+		return t.SetDeadline(deadline)
+	}
+	return NewTTransportExceptionDefaultString("Transport does not support deadlines")
+}
+
+/**
+ * Bounds a call made over transport by ctx.  The deadline of ctx is set on
+ * the transport, and if ctx is cancelled first the deadline is moved to now
+ * so that a blocked read or write returns; a transport that cannot take a
+ * deadline is interrupted instead.
+ *
+ * The returned function must be called with the outcome of the call once it
+ * is over.  It clears the deadline and, if the call failed after ctx was
+ * done, closes the transport, since the reply may still be on its way and
+ * would otherwise be read as the reply to the next call, and returns
+ * ctx.Err() in place of the error.
+ */
+func WatchContext(ctx context.Context, transport TTransport) func(err error) error {
+	if ctx.Done() == nil {
+		return func(err error) error { return err }
+	}
+	deadline, _ := ctx.Deadline()
+	hasDeadline := setTransportDeadline(transport, deadline) == nil
+	stop := make(chan bool)
+	stopped := make(chan bool)
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			if !hasDeadline || setTransportDeadline(transport, time.Now()) != nil {
+				interruptTransport(transport)
+			}
+		case <-stop:
+		}
+	}()
+	return func(err error) error {
+		close(stop)
+		<-stopped
+		if hasDeadline {
+			setTransportDeadline(transport, time.Time{})
+		}
+		ctxErr := ctx.Err()
+		if ctxErr == nil && !deadline.IsZero() && !time.Now().Before(deadline) {
+			// the transport deadline can fire before ctx notices
+			ctxErr = context.DeadlineExceeded
+		}
+		if err != nil && ctxErr != nil {
+			transport.Close()
+			return ctxErr
+		}
+		return err
+	}
+}
+
+/**
+ * Returns in wrapped so that processors reading from it see ctx as the
+ * context of the request; see RequestContext.
+ */
+func WithRequestContext(ctx context.Context, in TProtocol) TProtocol {
+	return &tContextProtocol{TProtocol: in, ctx: ctx}
+}
+
+/**
+ * Returns the context of the request being read from in, as passed to
+ * handlers by processors generated with the context option.  Requests
+ * served by the servers of this package get a context that carries the
+ * TConnectionInfo of the connection and is cancelled once the handler has
+ * returned, when the server shuts the connection or when the client hangs
+ * up; from the first call on, the connection is watched for the latter.
+ * Outside of a server, context.Background() is returned unless
+ * WithRequestContext was used.
+ */
+func RequestContext(in TProtocol) context.Context {
+	for ; in != nil; in = unwrapProtocol(in) {
+		if p, ok := in.(*tContextProtocol); ok {
+			if p.watch != nil {
+				p.watch()
+				p.watch = nil
+			}
+			return p.ctx
+		}
+	}
+	return context.Background()
+}
+
+/**
+ * Carries the request context through to the generated processor function.
+ */
+type tContextProtocol struct {
+	TProtocol
+	ctx   context.Context
+	watch func()
+}
@@ -0,0 +1,152 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+/**
+ * Hands out the context of every request; "wait" requests are only
+ * answered once their context is done.
+ */
+type contextProcessor struct {
+	contexts chan context.Context
+}
+
+func (p *contextProcessor) Process(in, out TProtocol) (bool, TException) {
+	name, _, seqid, err := in.ReadMessageBegin()
+	if err != nil {
+		return false, err
+	}
+	in.Skip(STRUCT)
+	in.ReadMessageEnd()
+	ctx := RequestContext(in)
+	p.contexts <- ctx
+	if name == "wait" {
+		<-ctx.Done()
+	}
+	out.WriteMessageBegin(name, REPLY, seqid)
+	out.WriteStructBegin("result")
+	out.WriteFieldStop()
+	out.WriteStructEnd()
+	out.WriteMessageEnd()
+	return true, out.Flush()
+}
+
+func TestRequestContextCancelledWhenClientHangsUp(t *testing.T) {
+	serverTransport, addr := listenForTest(t)
+	processor := &contextProcessor{contexts: make(chan context.Context, 1)}
+	server := NewTSimpleServer2(processor, serverTransport)
+	go server.Serve()
+	defer server.Stop()
+
+	trans, prot := openTestClient(t, addr)
+	writeEmptyCall(t, prot, "wait", 1)
+	ctx := <-processor.contexts
+	if ctx.Err() != nil {
+		t.Fatalf("Expected the request context to be live, but found %s", ctx.Err())
+	}
+	trans.Close()
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the request context to be cancelled when the client hung up")
+	}
+}
+
+func TestRequestContextWithPipelinedRequests(t *testing.T) {
+	serverTransport, addr := listenForTest(t)
+	processor := &contextProcessor{contexts: make(chan context.Context, 2)}
+	server := NewTThreadPoolServer2(processor, serverTransport)
+	go server.Serve()
+	defer server.Stop()
+
+	trans, prot := openTestClient(t, addr)
+	defer trans.Close()
+	writeEmptyCall(t, prot, "ping", 1)
+	writeEmptyCall(t, prot, "ping", 2)
+	for seqid := int32(1); seqid <= 2; seqid++ {
+		_, typeId, replySeqid, err := prot.ReadMessageBegin()
+		if err != nil || typeId != REPLY || replySeqid != seqid {
+			t.Fatalf("Expected REPLY with seqid %d, but found %d %d %v", seqid, typeId, replySeqid, err)
+		}
+		prot.Skip(STRUCT)
+		prot.ReadMessageEnd()
+	}
+	if ctx := <-processor.contexts; ctx.Err() == nil {
+		t.Fatalf("Expected the request context to be cancelled once the request was processed")
+	}
+}
+
+func TestRequestContextDefault(t *testing.T) {
+	if ctx := RequestContext(NewTBinaryProtocolTransport(NewTMemoryBuffer())); ctx != context.Background() {
+		t.Fatalf("Expected context.Background() outside of a server, but found %v", ctx)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	in := WithRequestContext(ctx, NewTBinaryProtocolTransport(NewTMemoryBuffer()))
+	if RequestContext(&tMessageRecordingProtocol{TProtocol: in}) != ctx {
+		t.Fatalf("Expected the context set with WithRequestContext")
+	}
+}
+
+func TestWatchContextInterruptsBlockedRead(t *testing.T) {
+	serverTransport, addr := listenForTest(t)
+	defer serverTransport.Close()
+	go serverTransport.Accept()
+
+	trans, _ := openTestClient(t, addr)
+	prot := NewTBinaryProtocolTransport(NewTFramedTransport(trans))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := WatchContext(ctx, prot.Transport())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	_, _, _, err := prot.ReadMessageBegin()
+	if err == nil {
+		t.Fatalf("Expected the read to be interrupted")
+	}
+	if err := done(err); err != context.Canceled {
+		t.Fatalf("Expected context.Canceled, but found %v", err)
+	}
+	if trans.IsOpen() {
+		t.Fatalf("Expected the transport to be closed after an abandoned call")
+	}
+}
+
+func TestWatchContextDeadline(t *testing.T) {
+	serverTransport, addr := listenForTest(t)
+	defer serverTransport.Close()
+	go serverTransport.Accept()
+
+	trans, prot := openTestClient(t, addr)
+	defer trans.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	done := WatchContext(ctx, trans)
+	_, _, _, err := prot.ReadMessageBegin()
+	if e, ok := err.(TTransportException); !ok || e.TypeId() != TIMED_OUT {
+		t.Fatalf("Expected TIMED_OUT, but found %v", err)
+	}
+	if err := done(err); err != context.DeadlineExceeded {
+		t.Fatalf("Expected context.DeadlineExceeded, but found %v", err)
+	}
+}
@@ -22,30 +22,69 @@ package thrift
 import (
 	"bytes"
 	"encoding/binary"
+	"strconv"
+	"time"
 )
 
+// largest frame read unless another maximum is given
+const DEFAULT_MAX_FRAME_SIZE = 16384000
+
+// frames up to this size are read into a buffer kept for the next frames
+const frameBufferReuseLimit = 1 << 20
+
+/**
+ * Sends each flushed message as a frame, prefixed by its size as a 4 byte
+ * big endian integer.
+ *
+ * Frames announcing more than the maximum frame size fail with a
+ * TTransportException before anything is allocated for them; the transport
+ * is out of step with its peer afterwards and should be closed.  Frames are
+ * read into a buffer that is reused for the frames that follow, so that
+ * reading them does not allocate once it has grown to fit.
+ */
 type TFramedTransport struct {
 	transport   TTransport
+	maxLength   int
 	writeBuffer *bytes.Buffer
-	readBuffer  *bytes.Buffer
+	readBuffer  bytes.Reader
+	frame       []byte
+	// separate so that one goroutine may read while another flushes
+	readHeader  [4]byte
+	writeHeader [4]byte
 }
 
 type tFramedTransportFactory struct {
-	factory TTransportFactory
+	factory   TTransportFactory
+	maxLength int
 }
 
 func NewTFramedTransportFactory(factory TTransportFactory) TTransportFactory {
-	return &tFramedTransportFactory{factory: factory}
+	return NewTFramedTransportFactoryMaxLength(factory, DEFAULT_MAX_FRAME_SIZE)
+}
+
+func NewTFramedTransportFactoryMaxLength(factory TTransportFactory, maxLength int) TTransportFactory {
+	return &tFramedTransportFactory{factory: factory, maxLength: maxLength}
 }
 
 func (p *tFramedTransportFactory) GetTransport(base TTransport) TTransport {
-	return NewTFramedTransport(p.factory.GetTransport(base))
+	return NewTFramedTransportMaxLength(p.factory.GetTransport(base), p.maxLength)
 }
 
 func NewTFramedTransport(transport TTransport) *TFramedTransport {
+	return NewTFramedTransportMaxLength(transport, DEFAULT_MAX_FRAME_SIZE)
+}
+
+/**
+ * Creates a framed transport refusing to read frames larger than maxLength
+ * bytes.
+ */
+func NewTFramedTransportMaxLength(transport TTransport, maxLength int) *TFramedTransport {
 	writeBuf := make([]byte, 0, 1024)
-	readBuf := make([]byte, 0, 1024)
-	return &TFramedTransport{transport: transport, writeBuffer: bytes.NewBuffer(writeBuf), readBuffer: bytes.NewBuffer(readBuf)}
+	return &TFramedTransport{transport: transport, maxLength: maxLength, writeBuffer: bytes.NewBuffer(writeBuf)}
+}
+
+func (p *TFramedTransport) MaxLength() int {
+	return p.maxLength
 }
 
 func (p *TFramedTransport) Open() error {
@@ -60,21 +99,27 @@ func (p *TFramedTransport) Peek() bool {
 	return p.transport.Peek()
 }
 
+/**
+ * Closes the transport underneath, dropping what was written but not
+ * flushed and what is left of the frame being read.
+ */
 func (p *TFramedTransport) Close() error {
+	p.writeBuffer.Reset()
+	p.readBuffer.Reset(nil)
 	return p.transport.Close()
 }
 
+func (p *TFramedTransport) SetDeadline(deadline time.Time) error {
+	return setTransportDeadline(p.transport, deadline)
+}
+
 func (p *TFramedTransport) Read(buf []byte) (int, error) {
-	if p.readBuffer.Len() > 0 {
-		got, err := p.readBuffer.Read(buf)
-		if got > 0 {
-			return got, NewTTransportExceptionFromOsError(err)
+	if p.readBuffer.Len() == 0 {
+		// Read another frame of data
+		if err := p.readFrame(); err != nil {
+			return 0, err
 		}
 	}
-
-	// Read another frame of data
-	p.readFrame()
-
 	got, err := p.readBuffer.Read(buf)
 	return got, NewTTransportExceptionFromOsError(err)
 }
@@ -90,9 +135,8 @@ func (p *TFramedTransport) Write(buf []byte) (int, error) {
 
 func (p *TFramedTransport) Flush() error {
 	size := p.writeBuffer.Len()
-	buf := []byte{0, 0, 0, 0}
-	binary.BigEndian.PutUint32(buf, uint32(size))
-	_, err := p.transport.Write(buf)
+	binary.BigEndian.PutUint32(p.writeHeader[:], uint32(size))
+	_, err := p.transport.Write(p.writeHeader[:])
 	if err != nil {
 		return NewTTransportExceptionFromOsError(err)
 	}
@@ -107,25 +151,32 @@ func (p *TFramedTransport) Flush() error {
 	return NewTTransportExceptionFromOsError(err)
 }
 
-func (p *TFramedTransport) readFrame() (int, error) {
-	buf := []byte{0, 0, 0, 0}
-	_, err := p.transport.ReadAll(buf)
-	if err != nil {
-		return 0, err
+func (p *TFramedTransport) readFrame() error {
+	if _, err := p.transport.ReadAll(p.readHeader[:]); err != nil {
+		return err
 	}
-	size := int(binary.BigEndian.Uint32(buf))
-	if size < 0 {
-		// TODO(pomack) log error
-		return 0, NewTTransportException(UNKNOWN_TRANSPORT_EXCEPTION, "Read a negative frame size ("+string(size)+")")
+	size := binary.BigEndian.Uint32(p.readHeader[:])
+	if int64(size) > int64(p.maxLength) {
+		return NewTTransportException(UNKNOWN_TRANSPORT_EXCEPTION, "Frame size "+strconv.FormatUint(uint64(size), 10)+" exceeds maximum of "+strconv.Itoa(p.maxLength))
 	}
-	if size == 0 {
-		return 0, nil
+	frame := p.frameBuffer(int(size))
+	if _, err := p.transport.ReadAll(frame); err != nil {
+		return err
 	}
-	buf2 := make([]byte, size)
-	n, err := p.transport.ReadAll(buf2)
-	if err != nil {
-		return n, err
+	p.readBuffer.Reset(frame)
+	return nil
+}
+
+/**
+ * Returns a buffer of size bytes to read a frame into, reusing the one of
+ * the previous frames unless the frame is too large to keep a buffer for.
+ */
+func (p *TFramedTransport) frameBuffer(size int) []byte {
+	if size > frameBufferReuseLimit {
+		return make([]byte, size)
+	}
+	if cap(p.frame) < size {
+		p.frame = make([]byte, size)
 	}
-	p.readBuffer = bytes.NewBuffer(buf2)
-	return size, nil
+	return p.frame[:size]
 }
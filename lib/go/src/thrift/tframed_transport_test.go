@@ -20,6 +20,9 @@
 package thrift
 
 import (
+	"encoding/binary"
+	"io"
+	"net"
 	"testing"
 )
 
@@ -27,3 +30,103 @@ func TestFramedTransport(t *testing.T) {
 	trans := NewTFramedTransport(NewTMemoryBuffer())
 	TransportTest(t, trans, trans)
 }
+
+func writeTestFrame(trans TTransport, size uint32, payload []byte) {
+	header := []byte{0, 0, 0, 0}
+	binary.BigEndian.PutUint32(header, size)
+	trans.Write(header)
+	trans.Write(payload)
+}
+
+func TestFramedTransportRefusesLargeFrames(t *testing.T) {
+	mem := NewTMemoryBuffer()
+	writeTestFrame(mem, 0x7fffffff, nil)
+	_, err := NewTFramedTransport(mem).Read(make([]byte, 1))
+	if e, ok := err.(TTransportException); !ok || e.TypeId() != UNKNOWN_TRANSPORT_EXCEPTION {
+		t.Fatalf("Expected a frame over the default maximum to be refused, but found %v", err)
+	}
+
+	mem = NewTMemoryBuffer()
+	writeTestFrame(mem, 10, make([]byte, 10))
+	if _, err := NewTFramedTransportMaxLength(mem, 8).Read(make([]byte, 10)); err == nil {
+		t.Fatalf("Expected a frame over the maximum to be refused")
+	}
+	mem = NewTMemoryBuffer()
+	writeTestFrame(mem, 10, make([]byte, 10))
+	if n, err := NewTFramedTransportMaxLength(mem, 10).ReadAll(make([]byte, 10)); n != 10 || err != nil {
+		t.Fatalf("Expected a frame of the maximum size to be read, but found %d %v", n, err)
+	}
+}
+
+func TestFramedTransportReusesReadBuffer(t *testing.T) {
+	const frames = 100
+	mem := NewTMemoryBuffer()
+	// one for the read below and one for the warm-up run of AllocsPerRun
+	for i := 0; i < frames+2; i++ {
+		writeTestFrame(mem, 64, transport_bdata[:64])
+	}
+	trans := NewTFramedTransport(mem)
+	buf := make([]byte, 64)
+	trans.ReadAll(buf)
+	allocs := testing.AllocsPerRun(frames, func() {
+		if _, err := trans.ReadAll(buf); err != nil {
+			t.Fatalf("Unable to read frame: %s", err)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("Expected reading frames not to allocate, but found %v allocations per frame", allocs)
+	}
+}
+
+func TestFramedTransportReadsWhileFlushing(t *testing.T) {
+	const frames = 100
+	local, remote := net.Pipe()
+	defer remote.Close()
+	go io.Copy(remote, remote)
+	socket, _ := NewTSocketConn(local)
+	trans := NewTFramedTransport(socket)
+	defer trans.Close()
+	flushed := make(chan bool)
+	go func() {
+		defer close(flushed)
+		for i := 1; i <= frames; i++ {
+			trans.Write(make([]byte, i))
+			trans.Flush()
+		}
+	}()
+	defer func() { <-flushed }()
+	for i := 1; i <= frames; i++ {
+		buf := make([]byte, i)
+		if n, err := trans.ReadAll(buf); n != i || err != nil {
+			t.Fatalf("Expected frame %d to be echoed, but found %d bytes %v", i, n, err)
+		}
+	}
+}
+
+/**
+ * Memory transport whose contents outlive Close.
+ */
+type keptMemoryTransport struct {
+	*TMemoryBuffer
+}
+
+func (p *keptMemoryTransport) Close() error {
+	return nil
+}
+
+func TestFramedTransportCloseDropsBufferedBytes(t *testing.T) {
+	mem := &keptMemoryTransport{TMemoryBuffer: NewTMemoryBuffer()}
+	writeTestFrame(mem, 4, []byte("old!"))
+	trans := NewTFramedTransport(mem)
+	trans.Read(make([]byte, 1))
+	trans.Write([]byte("stale"))
+	trans.Close()
+	trans.Open()
+
+	trans.Write([]byte("x"))
+	trans.Flush()
+	frame := make([]byte, 5)
+	if n, _ := trans.Read(frame); n != 1 || frame[0] != 'x' {
+		t.Fatalf("Expected only the frame flushed after reopening, but found %q", frame[:n])
+	}
+}
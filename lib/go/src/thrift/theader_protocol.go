@@ -0,0 +1,146 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+/**
+ * THeaderProtocol speaks binary or compact over a THeaderTransport,
+ * following the protocol id found in the header of each frame read, and
+ * exposes the headers of the frames read and written.
+ *
+ * Servers must use the same THeaderProtocol for input and output so that
+ * replies go out in the dialect the request came in; TSimpleServer and
+ * TNonblockingServer do so automatically.
+ */
+type THeaderProtocol struct {
+	TProtocol
+	transport  *THeaderTransport
+	protocolID THeaderProtocolID
+}
+
+type THeaderProtocolFactory struct {
+	protocolID THeaderProtocolID
+}
+
+func NewTHeaderProtocolFactory() *THeaderProtocolFactory {
+	return NewTHeaderProtocolFactoryProtocolID(THEADER_PROTOCOL_BINARY)
+}
+
+/**
+ * Creates a factory whose protocols write protocolID until a frame in
+ * another protocol is read.
+ */
+func NewTHeaderProtocolFactoryProtocolID(protocolID THeaderProtocolID) *THeaderProtocolFactory {
+	return &THeaderProtocolFactory{protocolID: protocolID}
+}
+
+func (p *THeaderProtocolFactory) GetProtocol(trans TTransport) TProtocol {
+	protocol := NewTHeaderProtocol(trans)
+	protocol.transport.SetProtocolID(p.protocolID)
+	protocol.resetProtocol()
+	return protocol
+}
+
+/**
+ * Wraps trans in a THeaderTransport unless it already is one.
+ */
+func NewTHeaderProtocol(trans TTransport) *THeaderProtocol {
+	t, ok := trans.(*THeaderTransport)
+	if !ok {
+		t = NewTHeaderTransport(trans)
+	}
+	p := &THeaderProtocol{transport: t}
+	p.protocolID = -1
+	p.resetProtocol()
+	return p
+}
+
+func (p *THeaderProtocol) resetProtocol() {
+	if p.protocolID == p.transport.ProtocolID() {
+		return
+	}
+	p.protocolID = p.transport.ProtocolID()
+	switch p.protocolID {
+	case THEADER_PROTOCOL_COMPACT:
+		p.TProtocol = NewTCompactProtocol(p.transport)
+	default:
+		p.TProtocol = NewTBinaryProtocol(p.transport, false, true)
+	}
+}
+
+/**
+ * Returns the THeaderProtocol a request is read from, looking through the
+ * wrappers the servers and processors of this package put around it, or
+ * nil if in is not a THeaderProtocol.  Handlers use it on the input
+ * protocol to read the headers of the request.
+ */
+func HeaderProtocol(in TProtocol) *THeaderProtocol {
+	for ; in != nil; in = unwrapProtocol(in) {
+		if p, ok := in.(*THeaderProtocol); ok {
+			return p
+		}
+	}
+	return nil
+}
+
+func (p *THeaderProtocol) HeaderTransport() *THeaderTransport {
+	return p.transport
+}
+
+func (p *THeaderProtocol) ReadHeaders() map[string]string {
+	return p.transport.ReadHeaders()
+}
+
+func (p *THeaderProtocol) ReadHeader(key string) (string, bool) {
+	return p.transport.ReadHeader(key)
+}
+
+func (p *THeaderProtocol) WriteHeaders() map[string]string {
+	return p.transport.WriteHeaders()
+}
+
+func (p *THeaderProtocol) SetWriteHeader(key, value string) {
+	p.transport.SetWriteHeader(key, value)
+}
+
+func (p *THeaderProtocol) ClearWriteHeaders() {
+	p.transport.ClearWriteHeaders()
+}
+
+func (p *THeaderProtocol) AddTransform(transform THeaderTransformID) error {
+	return p.transport.AddTransform(transform)
+}
+
+func (p *THeaderProtocol) WriteMessageBegin(name string, typeId TMessageType, seqid int32) TProtocolException {
+	p.resetProtocol()
+	p.transport.SetSequenceID(seqid)
+	return p.TProtocol.WriteMessageBegin(name, typeId, seqid)
+}
+
+func (p *THeaderProtocol) ReadMessageBegin() (name string, typeId TMessageType, seqid int32, err TProtocolException) {
+	if e := p.transport.ReadFrame(); e != nil {
+		return name, typeId, seqid, NewTProtocolExceptionFromOsError(e)
+	}
+	p.resetProtocol()
+	return p.TProtocol.ReadMessageBegin()
+}
+
+func (p *THeaderProtocol) Transport() TTransport {
+	return p.transport
+}
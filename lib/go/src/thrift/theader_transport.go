@@ -0,0 +1,520 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"time"
+)
+
+const (
+	THEADER_MAGIC          = 0x0fff
+	THEADER_MAX_FRAME_SIZE = 0x3fffffff
+	THEADER_INFO_KEYVALUE  = 1
+	THEADER_INFO_PKEYVALUE = 2
+)
+
+/**
+ * Protocol ids carried in the header of a THeaderTransport frame.
+ */
+type THeaderProtocolID int32
+
+const (
+	THEADER_PROTOCOL_BINARY  THeaderProtocolID = 0
+	THEADER_PROTOCOL_COMPACT THeaderProtocolID = 2
+)
+
+/**
+ * Transforms applied to the payload of a THeaderTransport frame.
+ */
+type THeaderTransformID int32
+
+const (
+	THEADER_TRANSFORM_NONE THeaderTransformID = 0
+	THEADER_TRANSFORM_ZLIB THeaderTransformID = 1
+)
+
+/**
+ * The framing spoken by the peer.  A THeaderTransport detects this on every
+ * frame it reads and answers in kind, so that old framed and unframed
+ * clients can talk to a header server.
+ */
+type THeaderClientType int
+
+const (
+	THEADER_CLIENT_HEADER THeaderClientType = iota
+	THEADER_CLIENT_FRAMED_BINARY
+	THEADER_CLIENT_UNFRAMED_BINARY
+	THEADER_CLIENT_FRAMED_COMPACT
+	THEADER_CLIENT_UNFRAMED_COMPACT
+)
+
+/**
+ * THeaderTransport is a framed transport whose frames carry a protocol id,
+ * a list of payload transforms and string key/value headers in addition to
+ * the payload.  It is wire compatible with the Java and C++ THeaderTransport.
+ *
+ * Frame layout (all integers big endian, varints as in TCompactProtocol):
+ *
+ *   length:i32 magic:i16(0x0fff) flags:i16 seqid:i32 headerSize:i16(in words)
+ *   protocolId:varint numTransforms:varint transformId:varint*
+ *   (infoId:varint infoData)* padding
+ *   payload
+ */
+type THeaderTransport struct {
+	transport   TTransport
+	reader      *bufio.Reader
+	frame       *bytes.Buffer
+	frameReader io.Reader
+	writeBuffer *bytes.Buffer
+
+	clientType      THeaderClientType
+	protocolID      THeaderProtocolID
+	seqId           uint32
+	flags           uint16
+	readHeaders     map[string]string
+	writeHeaders    map[string]string
+	readTransforms  []THeaderTransformID
+	writeTransforms []THeaderTransformID
+	// largest frame read, before or after decompressing it
+	maxFrameSize int
+}
+
+type tHeaderTransportFactory struct {
+	factory      TTransportFactory
+	maxFrameSize int
+}
+
+func NewTHeaderTransportFactory(factory TTransportFactory) TTransportFactory {
+	return NewTHeaderTransportFactoryMaxFrameSize(factory, THEADER_MAX_FRAME_SIZE)
+}
+
+func NewTHeaderTransportFactoryMaxFrameSize(factory TTransportFactory, maxFrameSize int) TTransportFactory {
+	return &tHeaderTransportFactory{factory: factory, maxFrameSize: maxFrameSize}
+}
+
+func (p *tHeaderTransportFactory) GetTransport(base TTransport) TTransport {
+	return NewTHeaderTransportMaxFrameSize(p.factory.GetTransport(base), p.maxFrameSize)
+}
+
+func NewTHeaderTransport(transport TTransport) *THeaderTransport {
+	return NewTHeaderTransportMaxFrameSize(transport, THEADER_MAX_FRAME_SIZE)
+}
+
+/**
+ * Creates a header transport refusing to read frames larger than
+ * maxFrameSize, before or after decompressing them.
+ */
+func NewTHeaderTransportMaxFrameSize(transport TTransport, maxFrameSize int) *THeaderTransport {
+	p := &THeaderTransport{
+		transport:    transport,
+		reader:       bufio.NewReader(transport),
+		frame:        bytes.NewBuffer(make([]byte, 0, 1024)),
+		writeBuffer:  bytes.NewBuffer(make([]byte, 0, 1024)),
+		clientType:   THEADER_CLIENT_HEADER,
+		protocolID:   THEADER_PROTOCOL_BINARY,
+		readHeaders:  make(map[string]string),
+		writeHeaders: make(map[string]string),
+	}
+	p.SetMaxFrameSize(maxFrameSize)
+	return p
+}
+
+func (p *THeaderTransport) MaxFrameSize() int {
+	return p.maxFrameSize
+}
+
+/**
+ * Sets the largest frame read, before or after decompressing it.  Values
+ * beyond THEADER_MAX_FRAME_SIZE are lowered to it.
+ */
+func (p *THeaderTransport) SetMaxFrameSize(maxFrameSize int) {
+	if maxFrameSize <= 0 || maxFrameSize > THEADER_MAX_FRAME_SIZE {
+		maxFrameSize = THEADER_MAX_FRAME_SIZE
+	}
+	p.maxFrameSize = maxFrameSize
+}
+
+func (p *THeaderTransport) Open() error {
+	return p.transport.Open()
+}
+
+func (p *THeaderTransport) IsOpen() bool {
+	return p.transport.IsOpen()
+}
+
+func (p *THeaderTransport) Peek() bool {
+	return p.transport.Peek()
+}
+
+func (p *THeaderTransport) Close() error {
+	return p.transport.Close()
+}
+
+/**
+ * The protocol used for the payload of the last frame read, or to be used
+ * for the next frame written.
+ */
+func (p *THeaderTransport) ProtocolID() THeaderProtocolID {
+	return p.protocolID
+}
+
+func (p *THeaderTransport) SetProtocolID(protocolID THeaderProtocolID) error {
+	if protocolID != THEADER_PROTOCOL_BINARY && protocolID != THEADER_PROTOCOL_COMPACT {
+		return NewTTransportException(UNKNOWN_TRANSPORT_EXCEPTION, "Unsupported header protocol id "+strconv.Itoa(int(protocolID)))
+	}
+	p.protocolID = protocolID
+	return nil
+}
+
+func (p *THeaderTransport) ClientType() THeaderClientType {
+	return p.clientType
+}
+
+/**
+ * Forces the framing used for writing.  Clients talking to servers that do
+ * not understand the header format can use this to fall back to plain
+ * framed or unframed messages.
+ */
+func (p *THeaderTransport) SetClientType(clientType THeaderClientType) {
+	p.clientType = clientType
+	switch clientType {
+	case THEADER_CLIENT_FRAMED_BINARY, THEADER_CLIENT_UNFRAMED_BINARY:
+		p.protocolID = THEADER_PROTOCOL_BINARY
+	case THEADER_CLIENT_FRAMED_COMPACT, THEADER_CLIENT_UNFRAMED_COMPACT:
+		p.protocolID = THEADER_PROTOCOL_COMPACT
+	}
+}
+
+func (p *THeaderTransport) SequenceID() int32 {
+	return int32(p.seqId)
+}
+
+func (p *THeaderTransport) SetSequenceID(seqId int32) {
+	p.seqId = uint32(seqId)
+}
+
+/**
+ * Headers received with the last frame read.
+ */
+func (p *THeaderTransport) ReadHeaders() map[string]string {
+	return p.readHeaders
+}
+
+func (p *THeaderTransport) ReadHeader(key string) (value string, ok bool) {
+	value, ok = p.readHeaders[key]
+	return value, ok
+}
+
+/**
+ * Headers to be sent with the next frame written.  They are cleared once
+ * the frame is flushed.
+ */
+func (p *THeaderTransport) WriteHeaders() map[string]string {
+	return p.writeHeaders
+}
+
+func (p *THeaderTransport) SetWriteHeader(key, value string) {
+	p.writeHeaders[key] = value
+}
+
+func (p *THeaderTransport) ClearWriteHeaders() {
+	p.writeHeaders = make(map[string]string)
+}
+
+/**
+ * Transforms applied to the payload of every frame written, in order.
+ */
+func (p *THeaderTransport) AddTransform(transform THeaderTransformID) error {
+	if transform != THEADER_TRANSFORM_ZLIB {
+		return NewTTransportException(UNKNOWN_TRANSPORT_EXCEPTION, "Unsupported header transform "+strconv.Itoa(int(transform)))
+	}
+	p.writeTransforms = append(p.writeTransforms, transform)
+	return nil
+}
+
+func (p *THeaderTransport) SetDeadline(deadline time.Time) error {
+	return setTransportDeadline(p.transport, deadline)
+}
+
+func (p *THeaderTransport) Read(buf []byte) (int, error) {
+	if p.frameReader == nil || (p.frameReader == p.frame && p.frame.Len() == 0) {
+		if err := p.ReadFrame(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := p.frameReader.Read(buf)
+	return n, NewTTransportExceptionFromOsError(err)
+}
+
+func (p *THeaderTransport) ReadAll(buf []byte) (int, error) {
+	return ReadAllTransport(p, buf)
+}
+
+/**
+ * Reads the next frame off the underlying transport, detecting whether the
+ * peer speaks the header format, framed or unframed binary or compact.
+ * Does nothing while data of the current frame remains unread.
+ */
+func (p *THeaderTransport) ReadFrame() error {
+	if p.frameReader == p.frame && p.frame.Len() > 0 {
+		return nil
+	}
+	preamble, err := p.reader.Peek(4)
+	if err != nil {
+		return NewTTransportExceptionFromOsError(err)
+	}
+	if preamble[0] == 0x80 && preamble[1] == 0x01 {
+		p.startUnframed(THEADER_CLIENT_UNFRAMED_BINARY, THEADER_PROTOCOL_BINARY)
+		return nil
+	}
+	if preamble[0] == COMPACT_PROTOCOL_ID && preamble[1]&COMPACT_VERSION_MASK == COMPACT_VERSION {
+		p.startUnframed(THEADER_CLIENT_UNFRAMED_COMPACT, THEADER_PROTOCOL_COMPACT)
+		return nil
+	}
+	size := binary.BigEndian.Uint32(preamble)
+	if uint64(size) > uint64(p.maxFrameSize) {
+		return NewTTransportException(UNKNOWN_TRANSPORT_EXCEPTION, "Frame size "+strconv.FormatUint(uint64(size), 10)+" exceeds maximum of "+strconv.Itoa(p.maxFrameSize))
+	}
+	if _, err = p.reader.Discard(4); err != nil {
+		return NewTTransportExceptionFromOsError(err)
+	}
+	p.frame.Reset()
+	if _, err = io.CopyN(p.frame, p.reader, int64(size)); err != nil {
+		return NewTTransportExceptionFromOsError(err)
+	}
+	p.frameReader = p.frame
+	data := p.frame.Bytes()
+	if len(data) < 2 {
+		return NewTTransportException(UNKNOWN_TRANSPORT_EXCEPTION, "Frame too short to identify its protocol")
+	}
+	switch {
+	case binary.BigEndian.Uint16(data) == THEADER_MAGIC:
+		p.clientType = THEADER_CLIENT_HEADER
+		return p.parseHeader()
+	case data[0] == 0x80 && data[1] == 0x01:
+		p.clientType = THEADER_CLIENT_FRAMED_BINARY
+		p.protocolID = THEADER_PROTOCOL_BINARY
+	case data[0] == COMPACT_PROTOCOL_ID && data[1]&COMPACT_VERSION_MASK == COMPACT_VERSION:
+		p.clientType = THEADER_CLIENT_FRAMED_COMPACT
+		p.protocolID = THEADER_PROTOCOL_COMPACT
+	default:
+		return NewTTransportException(UNKNOWN_TRANSPORT_EXCEPTION, "Could not detect client transport type")
+	}
+	p.readHeaders = make(map[string]string)
+	p.readTransforms = nil
+	return nil
+}
+
+func (p *THeaderTransport) startUnframed(clientType THeaderClientType, protocolID THeaderProtocolID) {
+	p.clientType = clientType
+	p.protocolID = protocolID
+	p.readHeaders = make(map[string]string)
+	p.readTransforms = nil
+	p.frameReader = p.reader
+}
+
+func (p *THeaderTransport) parseHeader() error {
+	var fixed [10]byte
+	if _, err := io.ReadFull(p.frame, fixed[:]); err != nil {
+		return NewTTransportException(UNKNOWN_TRANSPORT_EXCEPTION, "Frame too short for header")
+	}
+	p.flags = binary.BigEndian.Uint16(fixed[2:4])
+	p.seqId = binary.BigEndian.Uint32(fixed[4:8])
+	headerSize := int(binary.BigEndian.Uint16(fixed[8:10])) * 4
+	if headerSize > p.frame.Len() {
+		return NewTTransportException(UNKNOWN_TRANSPORT_EXCEPTION, "Header size "+strconv.Itoa(headerSize)+" is larger than frame")
+	}
+	header := bytes.NewReader(p.frame.Next(headerSize))
+	protocolID, err := binary.ReadUvarint(header)
+	if err != nil {
+		return NewTTransportExceptionFromOsError(err)
+	}
+	if err = p.SetProtocolID(THeaderProtocolID(protocolID)); err != nil {
+		return err
+	}
+	numTransforms, err := binary.ReadUvarint(header)
+	if err != nil {
+		return NewTTransportExceptionFromOsError(err)
+	}
+	p.readTransforms = make([]THeaderTransformID, 0, numTransforms)
+	for i := uint64(0); i < numTransforms; i++ {
+		transform, err := binary.ReadUvarint(header)
+		if err != nil {
+			return NewTTransportExceptionFromOsError(err)
+		}
+		if THeaderTransformID(transform) != THEADER_TRANSFORM_ZLIB {
+			return NewTTransportException(UNKNOWN_TRANSPORT_EXCEPTION, "Unsupported header transform "+strconv.FormatUint(transform, 10))
+		}
+		p.readTransforms = append(p.readTransforms, THeaderTransformID(transform))
+	}
+	p.readHeaders = make(map[string]string)
+	for header.Len() > 0 {
+		infoId, err := binary.ReadUvarint(header)
+		if err != nil {
+			return NewTTransportExceptionFromOsError(err)
+		}
+		if infoId != THEADER_INFO_KEYVALUE && infoId != THEADER_INFO_PKEYVALUE {
+			// padding, or an info type we do not understand
+			break
+		}
+		count, err := binary.ReadUvarint(header)
+		if err != nil {
+			return NewTTransportExceptionFromOsError(err)
+		}
+		for i := uint64(0); i < count; i++ {
+			key, err := readHeaderString(header)
+			if err != nil {
+				return err
+			}
+			value, err := readHeaderString(header)
+			if err != nil {
+				return err
+			}
+			p.readHeaders[key] = value
+		}
+	}
+	for i := len(p.readTransforms) - 1; i >= 0; i-- {
+		r, err := zlib.NewReader(p.frame)
+		if err != nil {
+			return NewTTransportExceptionFromOsError(err)
+		}
+		// a small frame may inflate to any size, so stop past the limit
+		payload, err := ioutil.ReadAll(io.LimitReader(r, int64(p.maxFrameSize)+1))
+		r.Close()
+		if err != nil {
+			return NewTTransportExceptionFromOsError(err)
+		}
+		if len(payload) > p.maxFrameSize {
+			return NewTTransportException(UNKNOWN_TRANSPORT_EXCEPTION, "Decompressed frame exceeds maximum of "+strconv.Itoa(p.maxFrameSize))
+		}
+		p.frame = bytes.NewBuffer(payload)
+		p.frameReader = p.frame
+	}
+	return nil
+}
+
+func readHeaderString(r *bytes.Reader) (string, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", NewTTransportExceptionFromOsError(err)
+	}
+	if size > uint64(r.Len()) {
+		return "", NewTTransportException(UNKNOWN_TRANSPORT_EXCEPTION, "Header string length "+strconv.FormatUint(size, 10)+" exceeds header size")
+	}
+	buf := make([]byte, size)
+	r.Read(buf)
+	return string(buf), nil
+}
+
+func (p *THeaderTransport) Write(buf []byte) (int, error) {
+	n, err := p.writeBuffer.Write(buf)
+	return n, NewTTransportExceptionFromOsError(err)
+}
+
+/**
+ * Writes the buffered payload as a single frame in the dialect of the peer
+ * and flushes the underlying transport.
+ */
+func (p *THeaderTransport) Flush() error {
+	defer p.writeBuffer.Reset()
+	var frame []byte
+	switch p.clientType {
+	case THEADER_CLIENT_UNFRAMED_BINARY, THEADER_CLIENT_UNFRAMED_COMPACT:
+		frame = p.writeBuffer.Bytes()
+	case THEADER_CLIENT_FRAMED_BINARY, THEADER_CLIENT_FRAMED_COMPACT:
+		frame = make([]byte, 4, 4+p.writeBuffer.Len())
+		binary.BigEndian.PutUint32(frame, uint32(p.writeBuffer.Len()))
+		frame = append(frame, p.writeBuffer.Bytes()...)
+	default:
+		var err error
+		if frame, err = p.headerFrame(); err != nil {
+			return err
+		}
+	}
+	if _, err := p.transport.Write(frame); err != nil {
+		return NewTTransportExceptionFromOsError(err)
+	}
+	return NewTTransportExceptionFromOsError(p.transport.Flush())
+}
+
+func (p *THeaderTransport) headerFrame() ([]byte, error) {
+	payload := p.writeBuffer.Bytes()
+	for _, transform := range p.writeTransforms {
+		if transform == THEADER_TRANSFORM_ZLIB {
+			var compressed bytes.Buffer
+			w := zlib.NewWriter(&compressed)
+			if _, err := w.Write(payload); err != nil {
+				return nil, NewTTransportExceptionFromOsError(err)
+			}
+			if err := w.Close(); err != nil {
+				return nil, NewTTransportExceptionFromOsError(err)
+			}
+			payload = compressed.Bytes()
+		}
+	}
+	var header bytes.Buffer
+	writeHeaderUvarint(&header, uint64(p.protocolID))
+	writeHeaderUvarint(&header, uint64(len(p.writeTransforms)))
+	for _, transform := range p.writeTransforms {
+		writeHeaderUvarint(&header, uint64(transform))
+	}
+	if len(p.writeHeaders) > 0 {
+		writeHeaderUvarint(&header, THEADER_INFO_KEYVALUE)
+		writeHeaderUvarint(&header, uint64(len(p.writeHeaders)))
+		for key, value := range p.writeHeaders {
+			writeHeaderUvarint(&header, uint64(len(key)))
+			header.WriteString(key)
+			writeHeaderUvarint(&header, uint64(len(value)))
+			header.WriteString(value)
+		}
+		p.ClearWriteHeaders()
+	}
+	for header.Len()%4 != 0 {
+		header.WriteByte(0)
+	}
+	if header.Len()/4 > 0xffff {
+		return nil, NewTTransportException(UNKNOWN_TRANSPORT_EXCEPTION, "Header is too large to be sent")
+	}
+	size := 10 + header.Len() + len(payload)
+	if size > THEADER_MAX_FRAME_SIZE {
+		return nil, NewTTransportException(UNKNOWN_TRANSPORT_EXCEPTION, "Frame size "+strconv.Itoa(size)+" exceeds maximum of "+strconv.Itoa(THEADER_MAX_FRAME_SIZE))
+	}
+	frame := make([]byte, 14, 4+size)
+	binary.BigEndian.PutUint32(frame[0:4], uint32(size))
+	binary.BigEndian.PutUint16(frame[4:6], THEADER_MAGIC)
+	binary.BigEndian.PutUint16(frame[6:8], p.flags)
+	binary.BigEndian.PutUint32(frame[8:12], p.seqId)
+	binary.BigEndian.PutUint16(frame[12:14], uint16(header.Len()/4))
+	frame = append(frame, header.Bytes()...)
+	return append(frame, payload...), nil
+}
+
+func writeHeaderUvarint(buf *bytes.Buffer, value uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], value)
+	buf.Write(tmp[:n])
+}
@@ -0,0 +1,188 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHeaderTransport(t *testing.T) {
+	trans := NewTHeaderTransport(NewTMemoryBuffer())
+	TransportTest(t, trans, trans)
+}
+
+func TestHeaderTransportZlib(t *testing.T) {
+	trans := NewTHeaderTransport(NewTMemoryBuffer())
+	trans.AddTransform(THEADER_TRANSFORM_ZLIB)
+	TransportTest(t, trans, trans)
+}
+
+func TestHeaderTransportZlibSizeLimit(t *testing.T) {
+	buf := NewTMemoryBuffer()
+	client := NewTHeaderTransport(buf)
+	client.AddTransform(THEADER_TRANSFORM_ZLIB)
+	client.Write(make([]byte, 64*1024))
+	if err := client.Flush(); err != nil {
+		t.Fatalf("Unable to flush: %s", err)
+	}
+	server := NewTHeaderTransportMaxFrameSize(buf, 32*1024)
+	if _, err := server.Read(make([]byte, 1)); err == nil {
+		t.Fatalf("Expected a frame inflating past the maximum frame size to be refused")
+	}
+}
+
+func TestHeaderProtocolHeadersAndCompact(t *testing.T) {
+	buf := NewTMemoryBuffer()
+	client := NewTHeaderProtocol(buf)
+	client.HeaderTransport().SetProtocolID(THEADER_PROTOCOL_COMPACT)
+	client.AddTransform(THEADER_TRANSFORM_ZLIB)
+	client.SetWriteHeader("trace-id", "abc123")
+	writeEmptyCall(t, client, "ping", 5)
+	if len(client.WriteHeaders()) != 0 {
+		t.Fatalf("Expected write headers to be cleared after flush, but found %v", client.WriteHeaders())
+	}
+
+	server := NewTHeaderProtocol(buf)
+	name, typeId, seqid, err := server.ReadMessageBegin()
+	if err != nil {
+		t.Fatalf("Unable to read message begin: %s", err)
+	}
+	if name != "ping" || typeId != CALL || seqid != 5 {
+		t.Fatalf("Expected ping CALL 5, but found %s %d %d", name, typeId, seqid)
+	}
+	if server.HeaderTransport().ProtocolID() != THEADER_PROTOCOL_COMPACT {
+		t.Fatalf("Expected compact protocol, but found %d", server.HeaderTransport().ProtocolID())
+	}
+	if v, ok := server.ReadHeader("trace-id"); !ok || v != "abc123" {
+		t.Fatalf("Expected trace-id header abc123, but found %q %v", v, ok)
+	}
+	if _, ok := server.TProtocol.(*TCompactProtocol); !ok {
+		t.Fatalf("Expected compact delegate, but found %T", server.TProtocol)
+	}
+}
+
+func TestHeaderProtocolFramedBinaryFallback(t *testing.T) {
+	buf := NewTMemoryBuffer()
+	framed := NewTBinaryProtocolTransport(NewTFramedTransport(buf))
+	writeEmptyCall(t, framed, "ping", 2)
+
+	processor := &recordingProcessor{}
+	server := NewTHeaderProtocol(buf)
+	if ok, err := processor.Process(server, server); !ok || err != nil {
+		t.Fatalf("Expected successful processing, but found %v %v", ok, err)
+	}
+	if server.HeaderTransport().ClientType() != THEADER_CLIENT_FRAMED_BINARY {
+		t.Fatalf("Expected framed binary client, but found %d", server.HeaderTransport().ClientType())
+	}
+	name, typeId, seqid, err := framed.ReadMessageBegin()
+	if err != nil || name != "ping" || typeId != REPLY || seqid != 2 {
+		t.Fatalf("Expected framed reply to ping 2, but found %s %d %d %v", name, typeId, seqid, err)
+	}
+}
+
+func TestHeaderProtocolUnframedCompactFallback(t *testing.T) {
+	buf := NewTMemoryBuffer()
+	unframed := NewTCompactProtocol(buf)
+	writeEmptyCall(t, unframed, "ping", 4)
+
+	processor := &recordingProcessor{}
+	server := NewTHeaderProtocol(buf)
+	if ok, err := processor.Process(server, server); !ok || err != nil {
+		t.Fatalf("Expected successful processing, but found %v %v", ok, err)
+	}
+	if server.HeaderTransport().ClientType() != THEADER_CLIENT_UNFRAMED_COMPACT {
+		t.Fatalf("Expected unframed compact client, but found %d", server.HeaderTransport().ClientType())
+	}
+	name, typeId, seqid, err := unframed.ReadMessageBegin()
+	if err != nil || name != "ping" || typeId != REPLY || seqid != 4 {
+		t.Fatalf("Expected unframed reply to ping 4, but found %s %d %d %v", name, typeId, seqid, err)
+	}
+}
+
+func TestHeaderTransportMaxFrameSize(t *testing.T) {
+	buf := NewTMemoryBuffer()
+	client := NewTHeaderTransport(buf)
+	client.Write(make([]byte, 1024))
+	if err := client.Flush(); err != nil {
+		t.Fatalf("Unable to flush: %s", err)
+	}
+	server := NewTHeaderTransport(buf)
+	server.SetMaxFrameSize(512)
+	if server.MaxFrameSize() != 512 {
+		t.Fatalf("Expected maximum frame size 512, but found %d", server.MaxFrameSize())
+	}
+	if _, err := server.Read(make([]byte, 1)); err == nil {
+		t.Fatalf("Expected a frame larger than the maximum frame size to be refused")
+	}
+}
+
+/**
+ * Replies to every call with an empty result, passing the trace-id header
+ * of the request on to headers.
+ */
+type headerReadingProcessor struct {
+	headers chan string
+}
+
+func (p *headerReadingProcessor) Process(in, out TProtocol) (bool, TException) {
+	name, _, seqid, err := in.ReadMessageBegin()
+	if err != nil {
+		return false, err
+	}
+	in.Skip(STRUCT)
+	in.ReadMessageEnd()
+	value := "<no header protocol>"
+	if headerProtocol := HeaderProtocol(in); headerProtocol != nil {
+		value, _ = headerProtocol.ReadHeader("trace-id")
+	}
+	p.headers <- value
+	out.WriteMessageBegin(name, REPLY, seqid)
+	out.WriteStructBegin("result")
+	out.WriteFieldStop()
+	out.WriteStructEnd()
+	out.WriteMessageEnd()
+	return true, out.Flush()
+}
+
+func TestHeaderProtocolReadInServedHandler(t *testing.T) {
+	serverTransport, addr := listenForTest(t)
+	processor := &headerReadingProcessor{headers: make(chan string, 1)}
+	server := NewTSimpleServer4(processor, serverTransport, NewTTransportFactory(), NewTHeaderProtocolFactory())
+	go server.Serve()
+	defer server.Stop()
+
+	trans, _ := openTestClient(t, addr)
+	defer trans.Close()
+	client := NewTHeaderProtocol(trans)
+	client.SetWriteHeader("trace-id", "abc123")
+	writeEmptyCall(t, client, "ping", 3)
+	select {
+	case value := <-processor.headers:
+		if value != "abc123" {
+			t.Fatalf("Expected the handler to read trace-id abc123, but found %q", value)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the handler to be called")
+	}
+	if _, typeId, seqid, err := client.ReadMessageBegin(); err != nil || typeId != REPLY || seqid != 3 {
+		t.Fatalf("Expected REPLY with seqid 3, but found %d %d %v", typeId, seqid, err)
+	}
+}
@@ -21,80 +21,177 @@ package thrift
 
 import (
 	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
+	"sync"
+	"time"
 )
 
+// how much of the body of a failed reply is quoted in the error
+const HTTP_ERROR_BODY_LIMIT = 1024
+
+/**
+ * Client side transport sending each request message as the body of a POST
+ * and reading the reply from the body of the response.
+ */
 type THttpClient struct {
-	response           *http.Response
-	url                *url.URL
-	requestBuffer      *bytes.Buffer
-	nsecConnectTimeout int64
-	nsecReadTimeout    int64
+	client        *http.Client
+	response      *http.Response
+	url           *url.URL
+	requestBuffer *bytes.Buffer
+	header        http.Header
+	timeout       time.Duration
+	// guards the deadline, which other goroutines may move mid-request
+	lock          sync.Mutex
+	cancel        context.CancelFunc
+	deadline      time.Time
+	deadlineTimer *time.Timer
+}
+
+/**
+ * How a THttpClient makes its requests.  Client defaults to
+ * http.DefaultClient; give one with its own http.Transport to control
+ * connection reuse and dial timeouts.  Header is sent with every request,
+ * for instance to authenticate or trace it.  Timeout bounds each request,
+ * from sending it to reading the whole reply; zero means no limit beyond
+ * that of Client.
+ */
+type THttpClientOptions struct {
+	Client  *http.Client
+	Header  http.Header
+	Timeout time.Duration
 }
 
 type THttpClientTransportFactory struct {
-	url    string
-	isPost bool
+	url     string
+	options THttpClientOptions
 }
 
 func (p *THttpClientTransportFactory) GetTransport(trans TTransport) TTransport {
 	if trans != nil {
 		t, ok := trans.(*THttpClient)
 		if ok && t.url != nil {
-			if t.requestBuffer != nil {
-				t2, _ := NewTHttpPostClient(t.url.String())
-				return t2
-			}
-			t2, _ := NewTHttpClient(t.url.String())
+			t2, _ := NewTHttpClientWithOptions(t.url.String(), THttpClientOptions{Client: t.client, Header: t.header, Timeout: t.timeout})
 			return t2
 		}
 	}
-	if p.isPost {
-		s, _ := NewTHttpPostClient(p.url)
-		return s
-	}
-	s, _ := NewTHttpClient(p.url)
+	s, _ := NewTHttpClientWithOptions(p.url, p.options)
 	return s
 }
 
 func NewTHttpClientTransportFactory(url string) *THttpClientTransportFactory {
-	return &THttpClientTransportFactory{url: url, isPost: false}
+	return NewTHttpClientTransportFactoryWithOptions(url, THttpClientOptions{})
 }
 
+/**
+ * Deprecated: same as NewTHttpClientTransportFactory.
+ */
 func NewTHttpPostClientTransportFactory(url string) *THttpClientTransportFactory {
-	return &THttpClientTransportFactory{url: url, isPost: true}
+	return NewTHttpClientTransportFactory(url)
+}
+
+func NewTHttpClientTransportFactoryWithOptions(url string, options THttpClientOptions) *THttpClientTransportFactory {
+	return &THttpClientTransportFactory{url: url, options: options}
 }
 
+/**
+ * Nothing is sent to urlstr until the first request is flushed.
+ */
 func NewTHttpClient(urlstr string) (TTransport, error) {
-	parsedURL, err := url.Parse(urlstr)
-	if err != nil {
-		return nil, err
-	}
-	response, err := http.Get(urlstr)
-	if err != nil {
-		return nil, err
-	}
-	return &THttpClient{response: response, url: parsedURL}, nil
+	return NewTHttpClientWithOptions(urlstr, THttpClientOptions{})
 }
 
+/**
+ * Deprecated: same as NewTHttpClient, which now also POSTs its requests.
+ */
 func NewTHttpPostClient(urlstr string) (TTransport, error) {
+	return NewTHttpClient(urlstr)
+}
+
+func NewTHttpClientWithOptions(urlstr string, options THttpClientOptions) (TTransport, error) {
 	parsedURL, err := url.Parse(urlstr)
 	if err != nil {
 		return nil, err
 	}
+	client := options.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	header := http.Header{}
+	for key, values := range options.Header {
+		header[key] = append([]string(nil), values...)
+	}
 	buf := make([]byte, 0, 1024)
-	return &THttpClient{url: parsedURL, requestBuffer: bytes.NewBuffer(buf)}, nil
+	return &THttpClient{client: client, url: parsedURL, requestBuffer: bytes.NewBuffer(buf), header: header, timeout: options.Timeout}, nil
+}
+
+/**
+ * Sets a header sent with every request from now on.
+ */
+func (p *THttpClient) SetHeader(key, value string) {
+	p.header.Set(key, value)
+}
+
+func (p *THttpClient) GetHeader(key string) string {
+	return p.header.Get(key)
+}
+
+func (p *THttpClient) DelHeader(key string) {
+	p.header.Del(key)
+}
+
+func (p *THttpClient) Timeout() time.Duration {
+	return p.timeout
+}
+
+/**
+ * Sets the bound on the requests flushed from now on.
+ */
+func (p *THttpClient) SetTimeout(timeout time.Duration) {
+	p.timeout = timeout
+}
+
+/**
+ * Bounds the request being sent or read, if any, and those flushed from
+ * now on, by deadline; a zero time clears it.  Reaching the deadline
+ * cancels the request, failing the Flush or Read blocked on it.  Safe to
+ * call from another goroutine while a request is in flight.
+ */
+func (p *THttpClient) SetDeadline(deadline time.Time) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.deadline = deadline
+	p.armDeadline()
+	return nil
+}
+
+// must be called with the lock held
+func (p *THttpClient) armDeadline() {
+	if p.deadlineTimer != nil {
+		p.deadlineTimer.Stop()
+		p.deadlineTimer = nil
+	}
+	if p.cancel != nil && !p.deadline.IsZero() {
+		p.deadlineTimer = time.AfterFunc(time.Until(p.deadline), p.cancel)
+	}
 }
 
+/**
+ * Makes a closed client usable again; requests are only sent on Flush.
+ */
 func (p *THttpClient) Open() error {
-	// do nothing
+	if p.requestBuffer == nil {
+		p.requestBuffer = bytes.NewBuffer(make([]byte, 0, 1024))
+	}
 	return nil
 }
 
 func (p *THttpClient) IsOpen() bool {
-	return p.response != nil || p.requestBuffer != nil
+	return p.requestBuffer != nil
 }
 
 func (p *THttpClient) Peek() bool {
@@ -102,16 +199,9 @@ func (p *THttpClient) Peek() bool {
 }
 
 func (p *THttpClient) Close() error {
-	if p.response != nil && p.response.Body != nil {
-		err := p.response.Body.Close()
-		p.response = nil
-		return err
-	}
-	if p.requestBuffer != nil {
-		p.requestBuffer.Reset()
-		p.requestBuffer = nil
-	}
-	return nil
+	err := p.closeResponse()
+	p.requestBuffer = nil
+	return err
 }
 
 func (p *THttpClient) Read(buf []byte) (int, error) {
@@ -119,6 +209,10 @@ func (p *THttpClient) Read(buf []byte) (int, error) {
 		return 0, NewTTransportException(NOT_OPEN, "Response buffer is empty, no request.")
 	}
 	n, err := p.response.Body.Read(buf)
+	if n > 0 && err == io.EOF {
+		// the rest of the reply was read; the next read reports the end
+		err = nil
+	}
 	return n, NewTTransportExceptionFromOsError(err)
 }
 
@@ -127,19 +221,85 @@ func (p *THttpClient) ReadAll(buf []byte) (int, error) {
 }
 
 func (p *THttpClient) Write(buf []byte) (int, error) {
+	if p.requestBuffer == nil {
+		return 0, NewTTransportException(NOT_OPEN, "HTTP client closed")
+	}
 	n, err := p.requestBuffer.Write(buf)
 	return n, err
 }
 
+/**
+ * Sends the request written so far.  A reply with a status other than 200
+ * fails with an error quoting the start of its body.
+ */
 func (p *THttpClient) Flush() error {
-	response, err := http.Post(p.url.String(), "application/x-thrift", p.requestBuffer)
+	if p.requestBuffer == nil {
+		return NewTTransportException(NOT_OPEN, "HTTP client closed")
+	}
+	p.closeResponse()
+	ctx, cancel := context.WithCancel(context.Background())
+	if p.timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, p.timeout)
+		cancelRequest := cancel
+		cancel = func() {
+			cancelTimeout()
+			cancelRequest()
+		}
+	}
+	p.lock.Lock()
+	p.cancel = cancel
+	p.armDeadline()
+	p.lock.Unlock()
+	// the request may still read its body once Do returns, so the next
+	// request is written to a buffer of its own
+	body := p.requestBuffer
+	p.requestBuffer = bytes.NewBuffer(make([]byte, 0, 1024))
+	request, err := http.NewRequestWithContext(ctx, "POST", p.url.String(), body)
+	if err != nil {
+		p.closeResponse()
+		return NewTTransportExceptionFromOsError(err)
+	}
+	for key, values := range p.header {
+		request.Header[key] = values
+	}
+	if request.Header.Get("Content-Type") == "" {
+		request.Header.Set("Content-Type", "application/x-thrift")
+	}
+	if request.Header.Get("Accept") == "" {
+		request.Header.Set("Accept", request.Header.Get("Content-Type"))
+	}
+	response, err := p.client.Do(request)
 	if err != nil {
+		p.closeResponse()
 		return NewTTransportExceptionFromOsError(err)
 	}
 	if response.StatusCode != http.StatusOK {
-		// TODO(pomack) log bad response
-		return NewTTransportException(UNKNOWN_TRANSPORT_EXCEPTION, "HTTP Response code: "+strconv.Itoa(response.StatusCode))
+		quoted, _ := ioutil.ReadAll(io.LimitReader(response.Body, HTTP_ERROR_BODY_LIMIT))
+		response.Body.Close()
+		p.closeResponse()
+		message := "HTTP Response code: " + strconv.Itoa(response.StatusCode)
+		if len(quoted) > 0 {
+			message += ": " + string(quoted)
+		}
+		return NewTTransportException(UNKNOWN_TRANSPORT_EXCEPTION, message)
 	}
 	p.response = response
 	return nil
 }
+
+func (p *THttpClient) closeResponse() error {
+	var err error
+	if p.response != nil && p.response.Body != nil {
+		err = p.response.Body.Close()
+	}
+	p.response = nil
+	p.lock.Lock()
+	if p.cancel != nil {
+		p.cancel()
+		p.cancel = nil
+	}
+	p.armDeadline()
+	p.lock.Unlock()
+	return err
+}
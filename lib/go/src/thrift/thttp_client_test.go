@@ -20,7 +20,11 @@
 package thrift
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestHttpClient(t *testing.T) {
@@ -35,3 +39,58 @@ func TestHttpClient(t *testing.T) {
 	}
 	TransportTest(t, trans, trans)
 }
+
+func TestHttpClientOptions(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Authorization") != "Bearer token" || r.Header.Get("X-Trace-Id") != "abc" {
+			http.Error(w, "missing headers", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("pong"))
+	}))
+	defer server.Close()
+
+	trans, err := NewTHttpClientWithOptions(server.URL, THttpClientOptions{
+		Client: server.Client(),
+		Header: http.Header{"Authorization": {"Bearer token"}},
+	})
+	if err != nil {
+		t.Fatalf("Unable to create HTTP client: %s", err)
+	}
+	if requests != 0 {
+		t.Fatalf("Expected no request before the first flush, but found %d", requests)
+	}
+	client := trans.(*THttpClient)
+	client.Write([]byte("ping"))
+	err = client.Flush()
+	if err == nil || !strings.Contains(err.Error(), "401") || !strings.Contains(err.Error(), "missing headers") {
+		t.Fatalf("Expected the status and body of the reply in the error, but found %v", err)
+	}
+	client.SetHeader("X-Trace-Id", "abc")
+	client.Write([]byte("ping"))
+	if err := client.Flush(); err != nil {
+		t.Fatalf("Expected the request to succeed with the headers, but found %s", err)
+	}
+	buf := make([]byte, 4)
+	if _, err := client.ReadAll(buf); err != nil || string(buf) != "pong" {
+		t.Fatalf("Expected pong, but found %q %v", buf, err)
+	}
+}
+
+func TestHttpClientTimeout(t *testing.T) {
+	release := make(chan bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	trans, _ := NewTHttpClientWithOptions(server.URL, THttpClientOptions{Timeout: 50 * time.Millisecond})
+	trans.Write([]byte("ping"))
+	err := trans.Flush()
+	if e, ok := err.(TTransportException); !ok || e.TypeId() != TIMED_OUT {
+		t.Fatalf("Expected TIMED_OUT, but found %v", err)
+	}
+}
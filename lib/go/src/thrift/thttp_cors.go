@@ -0,0 +1,125 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+ * Which web pages may call a handler made by NewThriftJSONHandler.
+ * AllowedOrigins lists the origins, such as "https://app.example.com",
+ * whose scripts may call it; "*" allows any.  With AllowCredentials the
+ * browser sends cookies and HTTP authentication along and lets the script
+ * see the reply, but only for origins listed by name: pages let in by "*"
+ * never get credentials.  AllowedHeaders lists the request headers scripts
+ * may set beyond Content-Type, and MaxAge is how long browsers may cache the
+ * answer to a preflight request.
+ */
+type TCORSOptions struct {
+	AllowedOrigins   []string
+	AllowCredentials bool
+	AllowedHeaders   []string
+	MaxAge           time.Duration
+}
+
+type tCORSHandler struct {
+	handler http.Handler
+	options TCORSOptions
+}
+
+/**
+ * Serves processor to browsers over XHR, as the Thrift JavaScript library
+ * calls it: the requests and replies are written with TJSONProtocol, and
+ * the CORS preflight requests of browsers are answered according to cors,
+ * so that pages from other origins can make calls.
+ */
+func NewThriftJSONHandler(processor TProcessor, cors TCORSOptions) http.Handler {
+	return NewTCORSHandler(NewThriftHandler(processor, NewTJSONProtocolFactory(), NewTJSONProtocolFactory()), cors)
+}
+
+/**
+ * Adds the CORS headers allowing the origins of options to handler, and
+ * answers preflight requests for it.  Requests from other origins are
+ * refused with 403 Forbidden without reaching handler.
+ */
+func NewTCORSHandler(handler http.Handler, options TCORSOptions) http.Handler {
+	return &tCORSHandler{handler: handler, options: options}
+}
+
+func (p *tCORSHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	preflight := r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != ""
+	if origin == "" {
+		p.handler.ServeHTTP(w, r)
+		return
+	}
+	w.Header().Add("Vary", "Origin")
+	named := p.named(origin)
+	if !named && !p.anyOrigin() {
+		// a simple POST would still reach the processor, so it is refused too
+		http.Error(w, "Origin not allowed", http.StatusForbidden)
+		return
+	}
+	if named {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		if p.options.AllowCredentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+	} else {
+		// credentials are only sent to an origin named explicitly
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	}
+	if !preflight {
+		p.handler.ServeHTTP(w, r)
+		return
+	}
+	if method := r.Header.Get("Access-Control-Request-Method"); method != "POST" {
+		http.Error(w, "Method "+method+" not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Methods", "POST")
+	w.Header().Set("Access-Control-Allow-Headers", strings.Join(append([]string{"Content-Type"}, p.options.AllowedHeaders...), ", "))
+	if p.options.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(p.options.MaxAge/time.Second)))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (p *tCORSHandler) named(origin string) bool {
+	for _, allowed := range p.options.AllowedOrigins {
+		if allowed == origin && allowed != "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *tCORSHandler) anyOrigin() bool {
+	for _, allowed := range p.options.AllowedOrigins {
+		if allowed == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestThriftJSONHandlerPreflight(t *testing.T) {
+	handler := NewThriftJSONHandler(&recordingProcessor{}, TCORSOptions{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowCredentials: true,
+		AllowedHeaders:   []string{"Authorization"},
+		MaxAge:           time.Minute,
+	})
+
+	request := httptest.NewRequest("OPTIONS", "/", nil)
+	request.Header.Set("Origin", "https://app.example.com")
+	request.Header.Set("Access-Control-Request-Method", "POST")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204 for the preflight, but found %d", recorder.Code)
+	}
+	for header, expected := range map[string]string{
+		"Access-Control-Allow-Origin":      "https://app.example.com",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Allow-Methods":     "POST",
+		"Access-Control-Allow-Headers":     "Content-Type, Authorization",
+		"Access-Control-Max-Age":           "60",
+	} {
+		if value := recorder.Header().Get(header); value != expected {
+			t.Fatalf("Expected %s: %s, but found %q", header, expected, value)
+		}
+	}
+
+	request.Header.Set("Origin", "https://evil.example.com")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusForbidden || recorder.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("Expected the preflight from another origin to be refused, but found %d", recorder.Code)
+	}
+}
+
+func TestThriftJSONHandlerCall(t *testing.T) {
+	processor := &recordingProcessor{}
+	handler := NewThriftJSONHandler(processor, TCORSOptions{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+	body := NewTMemoryBuffer()
+	writeEmptyCall(t, NewTJSONProtocol(body), "ping", 3)
+	request := httptest.NewRequest("POST", "/", body)
+	request.Header.Set("Origin", "https://app.example.com")
+	request.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Fatalf("Expected 200 allowing any origin, but found %d %v", recorder.Code, recorder.Header())
+	}
+	if credentials := recorder.Header().Get("Access-Control-Allow-Credentials"); credentials != "" {
+		t.Fatalf("Expected no credentials for an origin allowed by \"*\", but found %q", credentials)
+	}
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Fatalf("Expected a JSON reply, but found %s", contentType)
+	}
+	reply := NewTMemoryBuffer()
+	reply.Write(recorder.Body.Bytes())
+	name, typeId, seqid, err := NewTJSONProtocol(reply).ReadMessageBegin()
+	if err != nil || name != "ping" || typeId != REPLY || seqid != 3 {
+		t.Fatalf("Expected a JSON REPLY to ping with seqid 3, but found %s %d %d %v", name, typeId, seqid, err)
+	}
+}
+
+func TestThriftJSONHandlerRefusesOtherOrigins(t *testing.T) {
+	processor := &recordingProcessor{}
+	handler := NewThriftJSONHandler(processor, TCORSOptions{AllowedOrigins: []string{"https://app.example.com"}})
+	body := NewTMemoryBuffer()
+	writeEmptyCall(t, NewTJSONProtocol(body), "ping", 3)
+	request := httptest.NewRequest("POST", "/", body)
+	request.Header.Set("Origin", "https://evil.example.com")
+	request.Header.Set("Content-Type", "text/plain")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusForbidden {
+		t.Fatalf("Expected 403 for a call from another origin, but found %d", recorder.Code)
+	}
+	if processor.name != "" {
+		t.Fatalf("Expected the call from another origin not to reach the processor, but it called %s", processor.name)
+	}
+}
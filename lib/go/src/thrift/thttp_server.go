@@ -0,0 +1,104 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+)
+
+type tThriftHandler struct {
+	processor   TProcessor
+	inFactory   TProtocolFactory
+	outFactory  TProtocolFactory
+	maxBodySize int64
+	contentType string
+}
+
+/**
+ * Serves processor over HTTP, for THttpClient and the HTTP clients of the
+ * other Thrift languages.  Each POST carries one request message in its
+ * body, read with inFactory, and is answered with the reply written with
+ * outFactory.  The handler gets the context of the HTTP request through
+ * RequestContext.
+ */
+func NewThriftHandler(processor TProcessor, inFactory, outFactory TProtocolFactory) http.Handler {
+	return NewThriftHandlerMaxBodySize(processor, inFactory, outFactory, 0)
+}
+
+/**
+ * Like NewThriftHandler, but refuses request bodies larger than
+ * maxBodySize bytes with 413 Request Entity Too Large.  Zero means no
+ * limit.
+ */
+func NewThriftHandlerMaxBodySize(processor TProcessor, inFactory, outFactory TProtocolFactory, maxBodySize int64) http.Handler {
+	return &tThriftHandler{
+		processor:   processor,
+		inFactory:   inFactory,
+		outFactory:  outFactory,
+		maxBodySize: maxBodySize,
+		contentType: httpContentType(outFactory),
+	}
+}
+
+func (p *tThriftHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "Thrift requests must be sent with POST", http.StatusMethodNotAllowed)
+		return
+	}
+	if p.maxBodySize > 0 && r.ContentLength > p.maxBodySize {
+		http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	body := io.Reader(r.Body)
+	if p.maxBodySize > 0 {
+		body = io.LimitReader(r.Body, p.maxBodySize+1)
+	}
+	in := NewTMemoryBuffer()
+	if _, err := in.ReadFrom(body); err != nil {
+		http.Error(w, "Unable to read request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if p.maxBodySize > 0 && int64(in.Len()) > p.maxBodySize {
+		http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	out := NewTMemoryBuffer()
+	_, err := ProcessWithRecovery(p.processor, WithRequestContext(r.Context(), p.inFactory.GetProtocol(in)), p.outFactory.GetProtocol(out), nil)
+	if err != nil && out.Len() == 0 {
+		// not even an exception could be sent back, so the request was bad
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", p.contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(out.Len()))
+	out.WriteTo(w)
+}
+
+func httpContentType(protocolFactory TProtocolFactory) string {
+	switch protocolFactory.(type) {
+	case *TJSONProtocolFactory, *TSimpleJSONProtocolFactory:
+		return "application/json"
+	}
+	return "application/x-thrift"
+}
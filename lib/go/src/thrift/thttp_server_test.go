@@ -0,0 +1,93 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestThriftHandlerServesHttpClient(t *testing.T) {
+	processor := &recordingProcessor{}
+	server := httptest.NewServer(NewThriftHandler(processor, NewTBinaryProtocolFactoryDefault(), NewTBinaryProtocolFactoryDefault()))
+	defer server.Close()
+
+	trans, err := NewTHttpPostClient(server.URL)
+	if err != nil {
+		t.Fatalf("Unable to create HTTP client: %s", err)
+	}
+	defer trans.Close()
+	prot := NewTBinaryProtocolTransport(trans)
+	writeEmptyCall(t, prot, "ping", 7)
+	name, typeId, seqid, readErr := prot.ReadMessageBegin()
+	if readErr != nil || name != "ping" || typeId != REPLY || seqid != 7 {
+		t.Fatalf("Expected REPLY to ping with seqid 7, but found %s %d %d %v", name, typeId, seqid, readErr)
+	}
+	if processor.name != "ping" {
+		t.Fatalf("Expected the processor to see the call, but found %q", processor.name)
+	}
+}
+
+func TestThriftHandlerContentType(t *testing.T) {
+	for _, test := range []struct {
+		factory     TProtocolFactory
+		contentType string
+	}{
+		{NewTBinaryProtocolFactoryDefault(), "application/x-thrift"},
+		{NewTJSONProtocolFactory(), "application/json"},
+	} {
+		request := NewTMemoryBuffer()
+		writeEmptyCall(t, test.factory.GetProtocol(request), "ping", 1)
+		recorder := httptest.NewRecorder()
+		handler := NewThriftHandler(&recordingProcessor{}, test.factory, test.factory)
+		handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/", request))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected 200, but found %d: %s", recorder.Code, recorder.Body)
+		}
+		if contentType := recorder.Header().Get("Content-Type"); contentType != test.contentType {
+			t.Fatalf("Expected Content-Type %s, but found %s", test.contentType, contentType)
+		}
+	}
+}
+
+func TestThriftHandlerRejectsBadRequests(t *testing.T) {
+	factory := NewTBinaryProtocolFactoryDefault()
+	handler := NewThriftHandlerMaxBodySize(&recordingProcessor{}, factory, factory, 16)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("Expected 405 for GET, but found %d", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/", bytes.NewReader(make([]byte, 17))))
+	if recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected 413 for a body over the limit, but found %d", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/", bytes.NewReader([]byte{1})))
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for a body that is not a message, but found %d", recorder.Code)
+	}
+}
@@ -0,0 +1,142 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"strings"
+	"sync"
+)
+
+/**
+ * TMultiplexedProcessor is a TProcessor allowing a single server to provide
+ * multiple services.
+ *
+ * To do so, you instantiate the processor and then register additional
+ * processors with it, as shown in the following example:
+ *
+ *   processor := thrift.NewTMultiplexedProcessor()
+ *   processor.RegisterProcessor("Calculator", tutorial.NewCalculatorProcessor(calcHandler))
+ *   processor.RegisterProcessor("WeatherReport", tutorial.NewWeatherReportProcessor(weatherHandler))
+ *
+ *   server := thrift.NewTSimpleServer2(processor, serverTransport)
+ *   server.Serve()
+ *
+ * Clients must use a TMultiplexedProtocol with the matching service name.
+ */
+type TMultiplexedProcessor struct {
+	lock                sync.RWMutex
+	serviceProcessorMap map[string]TProcessor
+	defaultProcessor    TProcessor
+}
+
+func NewTMultiplexedProcessor() *TMultiplexedProcessor {
+	return &TMultiplexedProcessor{serviceProcessorMap: make(map[string]TProcessor)}
+}
+
+/**
+ * Register a service with this TMultiplexedProcessor.  This allows us to
+ * broker requests to individual services by using the service name to
+ * select them at request time.
+ *
+ * @param serviceName Name of a service, has to be identical to the name
+ * declared in the Thrift IDL, e.g. "WeatherReport".
+ * @param processor Implementation of a service, usually referred to as
+ * "handlers", e.g. WeatherReportHandler implementing WeatherReport.Iface.
+ */
+func (p *TMultiplexedProcessor) RegisterProcessor(serviceName string, processor TProcessor) {
+	p.lock.Lock()
+	p.serviceProcessorMap[serviceName] = processor
+	p.lock.Unlock()
+}
+
+/**
+ * Register a processor that handles messages whose name carries no service
+ * prefix, so that clients that do not multiplex can still be served.
+ */
+func (p *TMultiplexedProcessor) RegisterDefault(processor TProcessor) {
+	p.lock.Lock()
+	p.defaultProcessor = processor
+	p.lock.Unlock()
+}
+
+func (p *TMultiplexedProcessor) processorFor(name string) (processor TProcessor, methodName string, ok bool) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	index := strings.Index(name, MULTIPLEXED_SEPARATOR)
+	if index < 0 {
+		return p.defaultProcessor, name, p.defaultProcessor != nil
+	}
+	processor, ok = p.serviceProcessorMap[name[:index]]
+	return processor, name[index+len(MULTIPLEXED_SEPARATOR):], ok
+}
+
+/**
+ * This implementation of process performs the following steps:
+ *
+ * - Read the beginning of the message.
+ * - Extract the service name from the message.
+ * - Using the service name to locate the appropriate processor.
+ * - Dispatch to the processor, with a decorated instance of TProtocol
+ *   that allows readMessageBegin() to return the original message.
+ *
+ * An unknown service, or a message lacking a service name when no default
+ * processor is registered, is answered with an UNKNOWN_METHOD exception.
+ */
+func (p *TMultiplexedProcessor) Process(in, out TProtocol) (bool, TException) {
+	name, typeId, seqid, err := in.ReadMessageBegin()
+	if err != nil {
+		return false, err
+	}
+	if typeId != CALL && typeId != ONEWAY {
+		return false, NewTProtocolException(INVALID_DATA, "Multiplexed message must be a CALL or ONEWAY: "+name)
+	}
+	processor, methodName, ok := p.processorFor(name)
+	if !ok {
+		in.Skip(STRUCT)
+		in.ReadMessageEnd()
+		x := NewTApplicationException(UNKNOWN_METHOD, "Service name not found in message name: "+name+".  Did you forget to call RegisterProcessor()?")
+		out.WriteMessageBegin(name, EXCEPTION, seqid)
+		x.Write(out)
+		out.WriteMessageEnd()
+		out.Transport().Flush()
+		return false, x
+	}
+	return processor.Process(newTStoredMessageProtocol(in, methodName, typeId, seqid), out)
+}
+
+/**
+ * Our goal was to work with any protocol.  In order to do that, we needed
+ * to allow them to call readMessageBegin() and get a TMessage in exactly
+ * the standard format, without the service name prepended to TMessage.name.
+ */
+type tStoredMessageProtocol struct {
+	TProtocol
+	name   string
+	typeId TMessageType
+	seqid  int32
+}
+
+func newTStoredMessageProtocol(protocol TProtocol, name string, typeId TMessageType, seqid int32) *tStoredMessageProtocol {
+	return &tStoredMessageProtocol{TProtocol: protocol, name: name, typeId: typeId, seqid: seqid}
+}
+
+func (p *tStoredMessageProtocol) ReadMessageBegin() (name string, typeId TMessageType, seqid int32, err TProtocolException) {
+	return p.name, p.typeId, p.seqid, nil
+}
@@ -0,0 +1,153 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"testing"
+)
+
+type recordingProcessor struct {
+	name  string
+	seqid int32
+}
+
+func (p *recordingProcessor) Process(in, out TProtocol) (bool, TException) {
+	name, _, seqid, err := in.ReadMessageBegin()
+	if err != nil {
+		return false, err
+	}
+	in.Skip(STRUCT)
+	in.ReadMessageEnd()
+	p.name = name
+	p.seqid = seqid
+	out.WriteMessageBegin(name, REPLY, seqid)
+	out.WriteStructBegin("result")
+	out.WriteFieldStop()
+	out.WriteStructEnd()
+	out.WriteMessageEnd()
+	return true, out.Flush()
+}
+
+func writeEmptyCall(t *testing.T, oprot TProtocol, name string, seqid int32) {
+	if err := oprot.WriteMessageBegin(name, CALL, seqid); err != nil {
+		t.Fatalf("Unable to write message begin: %s", err)
+	}
+	oprot.WriteStructBegin("args")
+	oprot.WriteFieldStop()
+	oprot.WriteStructEnd()
+	oprot.WriteMessageEnd()
+	oprot.Flush()
+}
+
+/**
+ * Makes an empty ping call on prot and reads the reply, returning the first
+ * error, so that callers can check calls that are expected to fail.
+ */
+func pingCall(prot TProtocol) error {
+	if err := prot.WriteMessageBegin("ping", CALL, 1); err != nil {
+		return err
+	}
+	prot.WriteStructBegin("args")
+	prot.WriteFieldStop()
+	prot.WriteStructEnd()
+	prot.WriteMessageEnd()
+	if err := prot.Flush(); err != nil {
+		return err
+	}
+	if _, _, _, err := prot.ReadMessageBegin(); err != nil {
+		return err
+	}
+	prot.Skip(STRUCT)
+	return prot.ReadMessageEnd()
+}
+
+func TestMultiplexedProtocolPrefixesCalls(t *testing.T) {
+	trans := NewTMemoryBuffer()
+	mp := NewTMultiplexedProtocol(NewTBinaryProtocolTransport(trans), "Calculator")
+	writeEmptyCall(t, mp, "add", 7)
+	name, typeId, seqid, err := NewTBinaryProtocolTransport(trans).ReadMessageBegin()
+	if err != nil {
+		t.Fatalf("Unable to read message begin: %s", err)
+	}
+	if name != "Calculator:add" || typeId != CALL || seqid != 7 {
+		t.Fatalf("Expected Calculator:add CALL 7, but found %s %d %d", name, typeId, seqid)
+	}
+}
+
+func TestMultiplexedProcessorDispatchesByServiceName(t *testing.T) {
+	calculator := &recordingProcessor{}
+	weather := &recordingProcessor{}
+	processor := NewTMultiplexedProcessor()
+	processor.RegisterProcessor("Calculator", calculator)
+	processor.RegisterProcessor("WeatherReport", weather)
+
+	trans := NewTMemoryBuffer()
+	factory := NewTMultiplexedProtocolFactory(NewTBinaryProtocolFactoryDefault(), "WeatherReport")
+	writeEmptyCall(t, factory.GetProtocol(trans), "getTemperature", 3)
+	prot := NewTBinaryProtocolTransport(trans)
+	ok, err := processor.Process(prot, prot)
+	if !ok || err != nil {
+		t.Fatalf("Expected successful processing, but found %v %v", ok, err)
+	}
+	if calculator.name != "" {
+		t.Fatalf("Calculator should not have been called, but received %s", calculator.name)
+	}
+	if weather.name != "getTemperature" || weather.seqid != 3 {
+		t.Fatalf("Expected getTemperature with seqid 3, but found %s %d", weather.name, weather.seqid)
+	}
+	name, typeId, seqid, err := prot.ReadMessageBegin()
+	if err != nil || name != "getTemperature" || typeId != REPLY || seqid != 3 {
+		t.Fatalf("Expected reply to getTemperature 3, but found %s %d %d %v", name, typeId, seqid, err)
+	}
+}
+
+func TestMultiplexedProcessorUnknownService(t *testing.T) {
+	processor := NewTMultiplexedProcessor()
+	trans := NewTMemoryBuffer()
+	writeEmptyCall(t, NewTMultiplexedProtocol(NewTBinaryProtocolTransport(trans), "Missing"), "ping", 1)
+	prot := NewTBinaryProtocolTransport(trans)
+	ok, err := processor.Process(prot, prot)
+	if ok || err == nil {
+		t.Fatalf("Expected failure for unknown service, but found %v %v", ok, err)
+	}
+	_, typeId, seqid, _ := prot.ReadMessageBegin()
+	if typeId != EXCEPTION || seqid != 1 {
+		t.Fatalf("Expected EXCEPTION reply with seqid 1, but found %d %d", typeId, seqid)
+	}
+	x, _ := NewTApplicationExceptionDefault().Read(prot)
+	if x.TypeId() != UNKNOWN_METHOD {
+		t.Fatalf("Expected UNKNOWN_METHOD, but found %d", x.TypeId())
+	}
+}
+
+func TestMultiplexedProcessorDefault(t *testing.T) {
+	legacy := &recordingProcessor{}
+	processor := NewTMultiplexedProcessor()
+	processor.RegisterDefault(legacy)
+	trans := NewTMemoryBuffer()
+	prot := NewTBinaryProtocolTransport(trans)
+	writeEmptyCall(t, prot, "ping", 9)
+	if ok, err := processor.Process(prot, prot); !ok || err != nil {
+		t.Fatalf("Expected successful processing, but found %v %v", ok, err)
+	}
+	if legacy.name != "ping" || legacy.seqid != 9 {
+		t.Fatalf("Expected ping with seqid 9, but found %s %d", legacy.name, legacy.seqid)
+	}
+}
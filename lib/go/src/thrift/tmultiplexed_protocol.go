@@ -0,0 +1,99 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+/**
+ * Used to separate the service name from the method name in the message
+ * name of a multiplexed call.
+ */
+const MULTIPLEXED_SEPARATOR = ":"
+
+/**
+ * TMultiplexedProtocol is a protocol-independent concrete decorator that
+ * allows a Thrift client to communicate with a multiplexing Thrift server,
+ * by prepending the service name to the function name during function calls.
+ *
+ * NOTE: THIS IS NOT USED BY SERVERS.  On the server, use TMultiplexedProcessor
+ * to handle requests from a multiplexing client.
+ *
+ * This example uses a single socket transport to invoke two services:
+ *
+ *   trans := thrift.NewTSocketAddr(addr)
+ *   trans.Open()
+ *   protocol := thrift.NewTBinaryProtocolTransport(trans)
+ *
+ *   mp := thrift.NewTMultiplexedProtocol(protocol, "Calculator")
+ *   calc := tutorial.NewCalculatorClientProtocol(trans, mp, mp)
+ *
+ *   mp2 := thrift.NewTMultiplexedProtocol(protocol, "WeatherReport")
+ *   weather := tutorial.NewWeatherReportClientProtocol(trans, mp2, mp2)
+ *
+ *   calc.Add(2, 2)
+ *   weather.GetTemperature()
+ */
+type TMultiplexedProtocol struct {
+	TProtocol
+	serviceName string
+}
+
+type tMultiplexedProtocolFactory struct {
+	factory     TProtocolFactory
+	serviceName string
+}
+
+/**
+ * Wrap the specified protocol, allowing it to be used to communicate with a
+ * multiplexing server.  The serviceName is required as it is prepended to
+ * the message header so that the multiplexing server can broker the function
+ * call to the proper service.
+ *
+ * @param protocol Your communication protocol of choice, e.g. TBinaryProtocol
+ * @param serviceName The service name of the service communicating via this protocol
+ */
+func NewTMultiplexedProtocol(protocol TProtocol, serviceName string) *TMultiplexedProtocol {
+	return &TMultiplexedProtocol{TProtocol: protocol, serviceName: serviceName}
+}
+
+/**
+ * Wraps the protocols created by factory so that generated clients built
+ * with New*ClientFactory() talk to the named service on a multiplexing server.
+ */
+func NewTMultiplexedProtocolFactory(factory TProtocolFactory, serviceName string) TProtocolFactory {
+	return &tMultiplexedProtocolFactory{factory: factory, serviceName: serviceName}
+}
+
+func (p *tMultiplexedProtocolFactory) GetProtocol(trans TTransport) TProtocol {
+	return NewTMultiplexedProtocol(p.factory.GetProtocol(trans), p.serviceName)
+}
+
+func (p *TMultiplexedProtocol) ServiceName() string {
+	return p.serviceName
+}
+
+/**
+ * Prepends the service name to the function name, separated by
+ * MULTIPLEXED_SEPARATOR, for calls and oneway calls.
+ */
+func (p *TMultiplexedProtocol) WriteMessageBegin(name string, typeId TMessageType, seqid int32) TProtocolException {
+	if typeId == CALL || typeId == ONEWAY {
+		return p.TProtocol.WriteMessageBegin(p.serviceName+MULTIPLEXED_SEPARATOR+name, typeId, seqid)
+	}
+	return p.TProtocol.WriteMessageBegin(name, typeId, seqid)
+}
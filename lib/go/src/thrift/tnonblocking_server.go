@@ -33,8 +33,7 @@ import ()
  * method call has been read off the wire. Clients must also use TFramedTransport.
  */
 type TNonblockingServer struct {
-	/** Flag for stopping the server */
-	stopped bool
+	connections tServerConnections
 
 	processorFactory       TProcessorFactory
 	serverTransport        TServerTransport
@@ -42,6 +41,8 @@ type TNonblockingServer struct {
 	outputTransportFactory TTransportFactory
 	inputProtocolFactory   TProtocolFactory
 	outputProtocolFactory  TProtocolFactory
+	eventHandler           TServerEventHandler
+	logger                 TLogger
 }
 
 func NewTNonblockingServer2(processor TProcessor, serverTransport TServerTransport) *TNonblockingServer {
@@ -120,56 +121,75 @@ func (p *TNonblockingServer) OutputProtocolFactory() TProtocolFactory {
 	return p.outputProtocolFactory
 }
 
+func (p *TNonblockingServer) ServerEventHandler() TServerEventHandler {
+	return p.eventHandler
+}
+
+/**
+ * Sets the handler notified of server and connection events.  Must be called
+ * before Serve().
+ */
+func (p *TNonblockingServer) SetServerEventHandler(eventHandler TServerEventHandler) {
+	p.eventHandler = eventHandler
+}
+
+func (p *TNonblockingServer) Logger() TLogger {
+	return p.logger
+}
+
+/**
+ * Sets where panics recovered while processing are logged; LOGGER if nil.
+ */
+func (p *TNonblockingServer) SetLogger(logger TLogger) {
+	p.logger = logger
+}
+
 func (p *TNonblockingServer) Serve() error {
-	p.stopped = false
+	p.connections.start()
 	err := p.serverTransport.Listen()
 	if err != nil {
 		return err
 	}
-	for !p.stopped {
+	if p.eventHandler != nil {
+		p.eventHandler.PreServe()
+	}
+	for !p.connections.isStopped() {
 		client, err := p.serverTransport.Accept()
 		if err != nil {
+			if p.connections.isStopped() {
+				return nil
+			}
 			return err
 		}
-		if client != nil {
-			go p.processRequest(client)
+		if client == nil {
+			continue
+		}
+		if conn := p.connections.add(client); conn != nil {
+			go p.connections.serve(p, conn)
+		} else {
+			client.Close()
 		}
 	}
 	return nil
 }
 
 func (p *TNonblockingServer) Stop() error {
-	p.stopped = true
+	p.connections.stop()
 	p.serverTransport.Interrupt()
 	return nil
 }
 
-func (p *TNonblockingServer) IsStopped() bool {
-	return p.stopped
+/**
+ * Stops accepting connections and waits up to nsecTimeout for the
+ * connections being served to finish their current message.  Idle
+ * connections are closed straight away.
+ */
+func (p *TNonblockingServer) Shutdown(nsecTimeout int64) error {
+	p.connections.stop()
+	p.serverTransport.Interrupt()
+	return p.connections.drain(nsecTimeout)
 }
 
-func (p *TNonblockingServer) processRequest(client TTransport) {
-	processor := p.processorFactory.GetProcessor(client)
-	inputTransport := p.inputTransportFactory.GetTransport(client)
-	outputTransport := p.outputTransportFactory.GetTransport(client)
-	inputProtocol := p.inputProtocolFactory.GetProtocol(inputTransport)
-	outputProtocol := p.outputProtocolFactory.GetProtocol(outputTransport)
-	if inputTransport != nil {
-		defer inputTransport.Close()
-	}
-	if outputTransport != nil {
-		defer outputTransport.Close()
-	}
-	for {
-		ok, e := processor.Process(inputProtocol, outputProtocol)
-		if e != nil {
-			if !p.stopped {
-				// TODO(pomack) log error
-				break
-			}
-		}
-		if !ok {
-			break
-		}
-	}
+func (p *TNonblockingServer) IsStopped() bool {
+	return p.connections.isStopped()
 }
@@ -21,6 +21,7 @@ package thrift
 
 import (
 	"net"
+	"sync"
 	"time"
 )
 
@@ -34,6 +35,16 @@ type TNonblockingSocket struct {
 	 * Socket timeout
 	 */
 	nsecTimeout int64
+	/**
+	 * Guards the replacing of conn against Interrupt, which may be called
+	 * from other goroutines
+	 */
+	lock sync.Mutex
+	/**
+	 * Set once Interrupt has closed conn, so that Close does not close it
+	 * again
+	 */
+	interrupted bool
 }
 
 type TNonblockingSocketTransportFactory struct {
@@ -119,11 +130,14 @@ func (p *TNonblockingSocket) Open() error {
 		return NewTTransportException(NOT_OPEN, "Cannot open bad address.")
 	}
 
-	var err error
-	if p.conn, err = net.Dial(p.addr.Network(), p.addr.String()); err != nil {
+	conn, err := net.Dial(p.addr.Network(), p.addr.String())
+	if err != nil {
 		LOGGER.Print("Could not open socket", err.Error())
 		return NewTTransportException(NOT_OPEN, err.Error())
 	}
+	p.lock.Lock()
+	p.conn = conn
+	p.lock.Unlock()
 	return nil
 }
 
@@ -175,28 +189,48 @@ func (p *TNonblockingSocket) Addr() net.Addr {
 	return p.addr
 }
 
+/**
+ * Reports whether the socket is open and the other side has not hung up,
+ * as TSocket.Peek does.
+ */
 func (p *TNonblockingSocket) Peek() bool {
-	return p.IsOpen()
+	p.lock.Lock()
+	conn := p.conn
+	p.lock.Unlock()
+	return conn != nil && peekConn(conn)
 }
 
 /**
  * Closes the socket.
  */
 func (p *TNonblockingSocket) Close() error {
-	if p.conn != nil {
-		if err := p.conn.Close(); err != nil {
+	p.lock.Lock()
+	conn := p.conn
+	if p.interrupted {
+		conn = nil
+	}
+	p.conn = nil
+	p.interrupted = false
+	p.lock.Unlock()
+	if conn != nil {
+		if err := conn.Close(); err != nil {
 			LOGGER.Print("Could not close socket.", err.Error())
 			return err
 		}
-		p.conn = nil
 	}
 	return nil
 }
 
+/**
+ * Unblocks a pending Read or Write.  The socket stays in place until the
+ * goroutine using it calls Close.
+ */
 func (p *TNonblockingSocket) Interrupt() error {
-	if !p.IsOpen() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.conn == nil || p.interrupted {
 		return nil
 	}
-	// TODO(pomack) fix Interrupt as it is probably not right
-	return p.Close()
+	p.interrupted = true
+	return p.conn.Close()
 }
@@ -0,0 +1,151 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import ()
+
+/**
+ * A call being dispatched to a TProcessorFunction.  Args is the generated
+ * <method>Args struct once it has been read, and Result the generated
+ * <method>Result struct once the handler has returned without error.
+ */
+type TProcessorCall struct {
+	Name   string
+	SeqId  int32
+	Args   interface{}
+	Result interface{}
+
+	argsHooks []func(call *TProcessorCall) TApplicationException
+}
+
+/**
+ * Has hook called once Args is read and before the handler is called,
+ * hooks being called in the order they were added.  An exception returned
+ * by hook is sent to the client in place of calling the handler, e.g. to
+ * refuse a call the client is not authorized to make.
+ */
+func (p *TProcessorCall) OnArgs(hook func(call *TProcessorCall) TApplicationException) {
+	p.argsHooks = append(p.argsHooks, hook)
+}
+
+/**
+ * Wraps the processor function for the method called name.  The returned
+ * function should call next.Process; the TProcessorCall of the call is
+ * available from the input protocol through ProcessorCall(in), and OnArgs
+ * on it lets the middleware look at the arguments before the handler runs.
+ */
+type TProcessorMiddleware func(name string, next TProcessorFunction) TProcessorFunction
+
+/**
+ * Implemented by generated processors.
+ */
+type TProcessorFunctionMap interface {
+	AddToProcessorMap(name string, processorFunction TProcessorFunction)
+	ProcessorMap() map[string]TProcessorFunction
+}
+
+/**
+ * Adapts a function to the TProcessorFunction interface.
+ */
+type TProcessorFunctionFunc func(seqId int32, in, out TProtocol) (bool, TException)
+
+func (p TProcessorFunctionFunc) Process(seqId int32, in, out TProtocol) (bool, TException) {
+	return p(seqId, in, out)
+}
+
+/**
+ * Wraps every processor function of processor in the middleware chain, the
+ * first middleware being the outermost.  Functions added to the processor
+ * afterwards are not wrapped.
+ */
+func WrapProcessorFunctions(processor TProcessorFunctionMap, middleware ...TProcessorMiddleware) {
+	functions := make(map[string]TProcessorFunction)
+	for name, processorFunction := range processor.ProcessorMap() {
+		functions[name] = processorFunction
+	}
+	for name, processorFunction := range functions {
+		for i := len(middleware) - 1; i >= 0; i-- {
+			processorFunction = middleware[i](name, processorFunction)
+		}
+		processor.AddToProcessorMap(name, &tProcessorCallFunction{name: name, next: processorFunction})
+	}
+}
+
+/**
+ * Returns the call being processed with in as its input protocol, or nil
+ * outside of a wrapped processor function.  in may have been wrapped again
+ * by the server or processor since.
+ */
+func ProcessorCall(in TProtocol) *TProcessorCall {
+	for ; in != nil; in = unwrapProtocol(in) {
+		if p, ok := in.(*tProcessorCallProtocol); ok {
+			return p.call
+		}
+	}
+	return nil
+}
+
+/**
+ * Called by generated processor functions once the arguments are read.
+ * Returns the exception to reply with instead of calling the handler if a
+ * hook added through OnArgs refused the call.
+ */
+func RecordProcessorCallArgs(in TProtocol, args interface{}) TApplicationException {
+	call := ProcessorCall(in)
+	if call == nil {
+		return nil
+	}
+	call.Args = args
+	for _, hook := range call.argsHooks {
+		if x := hook(call); x != nil {
+			return x
+		}
+	}
+	return nil
+}
+
+/**
+ * Called by generated processor functions once the handler has returned.
+ */
+func RecordProcessorCallResult(in TProtocol, result interface{}) {
+	if call := ProcessorCall(in); call != nil {
+		call.Result = result
+	}
+}
+
+type tProcessorCallFunction struct {
+	name string
+	next TProcessorFunction
+}
+
+func (p *tProcessorCallFunction) Process(seqId int32, in, out TProtocol) (bool, TException) {
+	if ProcessorCall(in) == nil {
+		in = &tProcessorCallProtocol{TProtocol: in, call: &TProcessorCall{Name: p.name, SeqId: seqId}}
+	}
+	return p.next.Process(seqId, in, out)
+}
+
+/**
+ * Carries the TProcessorCall through to the generated processor function.
+ */
+type tProcessorCallProtocol struct {
+	TProtocol
+	call *TProcessorCall
+}
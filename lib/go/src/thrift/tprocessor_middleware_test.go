@@ -0,0 +1,162 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"context"
+	"testing"
+)
+
+type testProcessorMap struct {
+	processorMap map[string]TProcessorFunction
+}
+
+func (p *testProcessorMap) AddToProcessorMap(name string, processorFunction TProcessorFunction) {
+	p.processorMap[name] = processorFunction
+}
+
+func (p *testProcessorMap) ProcessorMap() map[string]TProcessorFunction {
+	return p.processorMap
+}
+
+func (p *testProcessorMap) Process(in, out TProtocol) (bool, TException) {
+	name, _, seqId, err := in.ReadMessageBegin()
+	if err != nil {
+		return false, err
+	}
+	return p.processorMap[name].Process(seqId, in, out)
+}
+
+/**
+ * Does what a generated processor function does, with strings standing in
+ * for the args and result structs.
+ */
+func pingProcessorFunction(seqId int32, in, out TProtocol) (bool, TException) {
+	in.Skip(STRUCT)
+	in.ReadMessageEnd()
+	if x := RecordProcessorCallArgs(in, "ping args"); x != nil {
+		out.WriteMessageBegin("ping", EXCEPTION, seqId)
+		x.Write(out)
+		out.WriteMessageEnd()
+		return true, out.Flush()
+	}
+	RecordProcessorCallResult(in, "ping result")
+	out.WriteMessageBegin("ping", REPLY, seqId)
+	out.WriteStructBegin("result")
+	out.WriteFieldStop()
+	out.WriteStructEnd()
+	out.WriteMessageEnd()
+	return true, out.Flush()
+}
+
+func TestWrapProcessorFunctions(t *testing.T) {
+	processor := &testProcessorMap{processorMap: make(map[string]TProcessorFunction)}
+	processor.AddToProcessorMap("ping", TProcessorFunctionFunc(pingProcessorFunction))
+	var trace []string
+	var seen *TProcessorCall
+	tracing := func(label string) TProcessorMiddleware {
+		return func(name string, next TProcessorFunction) TProcessorFunction {
+			return TProcessorFunctionFunc(func(seqId int32, in, out TProtocol) (bool, TException) {
+				trace = append(trace, label+" before "+name)
+				ok, err := next.Process(seqId, in, out)
+				trace = append(trace, label+" after "+name)
+				seen = ProcessorCall(in)
+				return ok, err
+			})
+		}
+	}
+	WrapProcessorFunctions(processor, tracing("outer"), tracing("inner"))
+
+	prot := NewTBinaryProtocolTransport(NewTMemoryBuffer())
+	writeEmptyCall(t, prot, "ping", 6)
+	if ok, err := processor.Process(prot, prot); !ok || err != nil {
+		t.Fatalf("Expected successful processing, but found %v %v", ok, err)
+	}
+	expected := []string{"outer before ping", "inner before ping", "inner after ping", "outer after ping"}
+	if len(trace) != len(expected) {
+		t.Fatalf("Expected trace %v, but found %v", expected, trace)
+	}
+	for i := range expected {
+		if trace[i] != expected[i] {
+			t.Fatalf("Expected trace %v, but found %v", expected, trace)
+		}
+	}
+	if seen == nil || seen.Name != "ping" || seen.SeqId != 6 || seen.Args != "ping args" || seen.Result != "ping result" {
+		t.Fatalf("Expected ping call 6 with args and result, but found %+v", seen)
+	}
+	if _, typeId, seqid, err := prot.ReadMessageBegin(); err != nil || typeId != REPLY || seqid != 6 {
+		t.Fatalf("Expected REPLY with seqid 6, but found %d %d %v", typeId, seqid, err)
+	}
+}
+
+func TestProcessorCallOnArgsRefusesCall(t *testing.T) {
+	processor := &testProcessorMap{processorMap: make(map[string]TProcessorFunction)}
+	processor.AddToProcessorMap("ping", TProcessorFunctionFunc(pingProcessorFunction))
+	var seen *TProcessorCall
+	WrapProcessorFunctions(processor, func(name string, next TProcessorFunction) TProcessorFunction {
+		return TProcessorFunctionFunc(func(seqId int32, in, out TProtocol) (bool, TException) {
+			seen = ProcessorCall(in)
+			seen.OnArgs(func(call *TProcessorCall) TApplicationException {
+				if call.Args != "ping args" {
+					t.Errorf("Expected the hook to see the args, but found %v", call.Args)
+				}
+				return NewTApplicationException(UNKNOWN_APPLICATION_EXCEPTION, "not allowed")
+			})
+			return next.Process(seqId, in, out)
+		})
+	})
+
+	prot := NewTBinaryProtocolTransport(NewTMemoryBuffer())
+	writeEmptyCall(t, prot, "ping", 7)
+	if ok, err := processor.Process(prot, prot); !ok || err != nil {
+		t.Fatalf("Expected the refused call to leave the connection usable, but found %v %v", ok, err)
+	}
+	if seen == nil || seen.Result != nil {
+		t.Fatalf("Expected the handler not to be reached, but found %+v", seen)
+	}
+	if _, typeId, seqid, err := prot.ReadMessageBegin(); err != nil || typeId != EXCEPTION || seqid != 7 {
+		t.Fatalf("Expected EXCEPTION with seqid 7, but found %d %d %v", typeId, seqid, err)
+	}
+	if x, _ := NewTApplicationExceptionDefault().Read(prot); x.Error() != "not allowed" {
+		t.Fatalf("Expected the exception returned by the hook, but found %v", x)
+	}
+}
+
+func TestProcessorCallThroughRewrappedProtocol(t *testing.T) {
+	processor := &testProcessorMap{processorMap: make(map[string]TProcessorFunction)}
+	processor.AddToProcessorMap("ping", TProcessorFunctionFunc(pingProcessorFunction))
+	var seen *TProcessorCall
+	WrapProcessorFunctions(processor, func(name string, next TProcessorFunction) TProcessorFunction {
+		return TProcessorFunctionFunc(func(seqId int32, in, out TProtocol) (bool, TException) {
+			ok, err := next.Process(seqId, WithRequestContext(RequestContext(in), in), out)
+			seen = ProcessorCall(in)
+			return ok, err
+		})
+	})
+
+	prot := NewTBinaryProtocolTransport(NewTMemoryBuffer())
+	writeEmptyCall(t, prot, "ping", 8)
+	if ok, err := ProcessWithRecovery(processor, WithRequestContext(context.Background(), prot), prot, nil); !ok || err != nil {
+		t.Fatalf("Expected successful processing, but found %v %v", ok, err)
+	}
+	if seen == nil || seen.Args != "ping args" || seen.Result != "ping result" {
+		t.Fatalf("Expected args and result recorded through the wrapped protocol, but found %+v", seen)
+	}
+}
@@ -0,0 +1,107 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+)
+
+/**
+ * Where servers report errors they cannot hand back to a caller.
+ * *log.Logger satisfies this interface; the default is LOGGER.
+ */
+type TLogger interface {
+	Print(v ...interface{})
+}
+
+/**
+ * Calls processor.Process, recovering from a panic in the processor or the
+ * handler behind it.  The panic and its stack are logged to logger (LOGGER
+ * if nil) and, if the request message had been read and was not ONEWAY, an
+ * INTERNAL_ERROR TApplicationException is sent in reply with the request's
+ * seqid, unless a reply had already been started on out.  Behind a TMultiplexedProcessor the reply carries the method name
+ * without the service prefix, as regular replies do.  After a
+ * panic ok is false, since the input may be left in the middle of a message,
+ * so the caller should close the connection.
+ */
+func ProcessWithRecovery(processor TProcessor, in, out TProtocol, logger TLogger) (ok bool, err TException) {
+	recorder := &tMessageRecordingProtocol{TProtocol: in}
+	replier := &tReplyRecordingProtocol{TProtocol: out}
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		if logger == nil {
+			logger = LOGGER
+		}
+		logger.Print("Panic while processing ", recorder.name, ": ", r, "\n", string(debug.Stack()))
+		x := NewTApplicationException(INTERNAL_ERROR, fmt.Sprint("Internal error processing ", recorder.name, ": ", r))
+		// an exception after part of a reply would only garble it further
+		if recorder.read && recorder.typeId != ONEWAY && !replier.written {
+			name := recorder.name
+			if index := strings.Index(name, MULTIPLEXED_SEPARATOR); index >= 0 {
+				name = name[index+len(MULTIPLEXED_SEPARATOR):]
+			}
+			out.WriteMessageBegin(name, EXCEPTION, recorder.seqid)
+			x.Write(out)
+			out.WriteMessageEnd()
+			out.Flush()
+		}
+		ok, err = false, x
+	}()
+	return processor.Process(recorder, replier)
+}
+
+/**
+ * Remembers whether a reply was started, which every reply does with
+ * WriteMessageBegin.
+ */
+type tReplyRecordingProtocol struct {
+	TProtocol
+	written bool
+}
+
+func (p *tReplyRecordingProtocol) WriteMessageBegin(name string, typeId TMessageType, seqid int32) TProtocolException {
+	p.written = true
+	return p.TProtocol.WriteMessageBegin(name, typeId, seqid)
+}
+
+/**
+ * Remembers the last message begin read so that a reply can still be
+ * addressed to it after a panic.
+ */
+type tMessageRecordingProtocol struct {
+	TProtocol
+	read   bool
+	name   string
+	typeId TMessageType
+	seqid  int32
+}
+
+func (p *tMessageRecordingProtocol) ReadMessageBegin() (name string, typeId TMessageType, seqid int32, err TProtocolException) {
+	name, typeId, seqid, err = p.TProtocol.ReadMessageBegin()
+	if err == nil {
+		p.read, p.name, p.typeId, p.seqid = true, name, typeId, seqid
+	}
+	return name, typeId, seqid, err
+}
@@ -0,0 +1,153 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type panickingProcessor struct{}
+
+func (p *panickingProcessor) Process(in, out TProtocol) (bool, TException) {
+	name, _, _, err := in.ReadMessageBegin()
+	if err != nil {
+		return false, err
+	}
+	if name == "boom" {
+		panic("handler exploded")
+	}
+	in.Skip(STRUCT)
+	in.ReadMessageEnd()
+	out.WriteMessageBegin(name, REPLY, 1)
+	out.WriteStructBegin("result")
+	if name == "late boom" {
+		panic("handler exploded while replying")
+	}
+	out.WriteFieldStop()
+	out.WriteStructEnd()
+	out.WriteMessageEnd()
+	return true, out.Flush()
+}
+
+type bufferLogger struct {
+	lock  sync.Mutex
+	lines []string
+}
+
+func (p *bufferLogger) Print(v ...interface{}) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.lines = append(p.lines, fmt.Sprint(v...))
+}
+
+func TestProcessWithRecovery(t *testing.T) {
+	in := NewTBinaryProtocolTransport(NewTMemoryBuffer())
+	prot := NewTBinaryProtocolTransport(NewTMemoryBuffer())
+	writeEmptyCall(t, in, "boom", 11)
+	logger := &bufferLogger{}
+	ok, err := ProcessWithRecovery(&panickingProcessor{}, in, prot, logger)
+	if ok || err == nil {
+		t.Fatalf("Expected failure after panic, but found %v %v", ok, err)
+	}
+	name, typeId, seqid, e := prot.ReadMessageBegin()
+	if e != nil || name != "boom" || typeId != EXCEPTION || seqid != 11 {
+		t.Fatalf("Expected EXCEPTION reply to boom 11, but found %s %d %d %v", name, typeId, seqid, e)
+	}
+	x, _ := NewTApplicationExceptionDefault().Read(prot)
+	if x.TypeId() != INTERNAL_ERROR || !strings.Contains(x.Error(), "handler exploded") {
+		t.Fatalf("Expected INTERNAL_ERROR mentioning the panic, but found %d %s", x.TypeId(), x.Error())
+	}
+	if len(logger.lines) != 1 || !strings.Contains(logger.lines[0], "goroutine") {
+		t.Fatalf("Expected the panic and its stack to be logged, but found %v", logger.lines)
+	}
+}
+
+func TestNonblockingServerSurvivesPanic(t *testing.T) {
+	serverTransport, addr := listenForTest(t)
+	server := NewTNonblockingServer2(&panickingProcessor{}, serverTransport)
+	server.SetLogger(&bufferLogger{})
+	go server.Serve()
+	defer server.Stop()
+
+	trans, prot := openTestClient(t, addr)
+	defer trans.Close()
+	writeEmptyCall(t, prot, "boom", 12)
+	if _, typeId, seqid, err := prot.ReadMessageBegin(); err != nil || typeId != EXCEPTION || seqid != 12 {
+		t.Fatalf("Expected EXCEPTION reply with seqid 12, but found %d %d %v", typeId, seqid, err)
+	}
+
+	other, otherProt := openTestClient(t, addr)
+	defer other.Close()
+	writeEmptyCall(t, otherProt, "ping", 1)
+	if _, typeId, _, err := otherProt.ReadMessageBegin(); err != nil || typeId != REPLY {
+		t.Fatalf("Expected the server to keep serving, but found %d %v", typeId, err)
+	}
+}
+
+func TestProcessWithRecoveryOneway(t *testing.T) {
+	in := NewTBinaryProtocolTransport(NewTMemoryBuffer())
+	out := NewTMemoryBuffer()
+	in.WriteMessageBegin("boom", ONEWAY, 13)
+	in.WriteStructBegin("args")
+	in.WriteFieldStop()
+	in.WriteStructEnd()
+	in.WriteMessageEnd()
+	if ok, err := ProcessWithRecovery(&panickingProcessor{}, in, NewTBinaryProtocolTransport(out), &bufferLogger{}); ok || err == nil {
+		t.Fatalf("Expected failure after panic, but found %v %v", ok, err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("Expected no reply to a oneway call, but found %d bytes", out.Len())
+	}
+}
+
+func TestProcessWithRecoveryMultiplexed(t *testing.T) {
+	processor := NewTMultiplexedProcessor()
+	processor.RegisterProcessor("Calculator", &panickingProcessor{})
+	in := NewTBinaryProtocolTransport(NewTMemoryBuffer())
+	prot := NewTBinaryProtocolTransport(NewTMemoryBuffer())
+	writeEmptyCall(t, NewTMultiplexedProtocol(in, "Calculator"), "boom", 14)
+	ProcessWithRecovery(processor, in, prot, &bufferLogger{})
+	if name, typeId, seqid, err := prot.ReadMessageBegin(); err != nil || name != "boom" || typeId != EXCEPTION || seqid != 14 {
+		t.Fatalf("Expected EXCEPTION reply to boom 14, but found %s %d %d %v", name, typeId, seqid, err)
+	}
+}
+
+func TestProcessWithRecoveryAfterReplyStarted(t *testing.T) {
+	in := NewTBinaryProtocolTransport(NewTMemoryBuffer())
+	out := NewTMemoryBuffer()
+	prot := NewTBinaryProtocolTransport(out)
+	writeEmptyCall(t, in, "late boom", 15)
+	logger := &bufferLogger{}
+	if ok, err := ProcessWithRecovery(&panickingProcessor{}, in, prot, logger); ok || err == nil {
+		t.Fatalf("Expected failure after panic, but found %v %v", ok, err)
+	}
+	if _, typeId, _, err := prot.ReadMessageBegin(); err != nil || typeId != REPLY {
+		t.Fatalf("Expected the reply started by the processor, but found %d %v", typeId, err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("Expected no exception after the started reply, but found %d more bytes", out.Len())
+	}
+	if len(logger.lines) != 1 || !strings.Contains(logger.lines[0], "while replying") {
+		t.Fatalf("Expected the panic to be logged, but found %v", logger.lines)
+	}
+}
@@ -198,3 +198,24 @@ func Skip(self TProtocol, fieldType TType, maxDepth int) (err TProtocolException
 	}
 	return nil
 }
+
+/**
+ * Returns the protocol in wraps if in is one of the wrappers the servers
+ * and processors of this package put around the input and output
+ * protocols, or nil.
+ */
+func unwrapProtocol(in TProtocol) TProtocol {
+	switch p := in.(type) {
+	case *tContextProtocol:
+		return p.TProtocol
+	case *tProcessorCallProtocol:
+		return p.TProtocol
+	case *tMessageRecordingProtocol:
+		return p.TProtocol
+	case *tStoredMessageProtocol:
+		return p.TProtocol
+	case *tReplyRecordingProtocol:
+		return p.TProtocol
+	}
+	return nil
+}
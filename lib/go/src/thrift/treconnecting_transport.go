@@ -0,0 +1,191 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+const (
+	DEFAULT_RECONNECT_MAX_ATTEMPTS    = 5
+	DEFAULT_RECONNECT_INITIAL_BACKOFF = 100 * time.Millisecond
+	DEFAULT_RECONNECT_MAX_BACKOFF     = 10 * time.Second
+	// shortest wait between dials, so that a reconnect never spins
+	MIN_RECONNECT_BACKOFF = time.Millisecond
+)
+
+/**
+ * Told about every successful re-dial: attempts is the number of dials it
+ * took and cause the failure that broke the previous connection, nil if the
+ * transport had been closed rather than broken.
+ */
+type TReconnectFunc func(attempts int, cause error)
+
+/**
+ * Wraps a TSocket or TNonblockingSocket so that a client survives the
+ * server going away.  Once a read, write or flush fails, the socket is
+ * closed and the failing call returns the error as usual; the next write,
+ * which starts the next request, dials again.  Failed dials are retried up
+ * to MaxAttempts times, waiting between them for a backoff that doubles
+ * from InitialBackoff up to MaxBackoff, with up to half of it taken off at
+ * random so that clients do not all come back at once.
+ *
+ * A closed transport, including one closed by a generated client after a
+ * timeout, is dialed again in the same way.
+ */
+type TReconnectingTransport struct {
+	transport      TTransport
+	cause          error
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	onReconnect    TReconnectFunc
+}
+
+func NewTReconnectingTransport(transport TTransport) *TReconnectingTransport {
+	return &TReconnectingTransport{
+		transport:      transport,
+		maxAttempts:    DEFAULT_RECONNECT_MAX_ATTEMPTS,
+		initialBackoff: DEFAULT_RECONNECT_INITIAL_BACKOFF,
+		maxBackoff:     DEFAULT_RECONNECT_MAX_BACKOFF,
+	}
+}
+
+func (p *TReconnectingTransport) Transport() TTransport {
+	return p.transport
+}
+
+func (p *TReconnectingTransport) MaxAttempts() int {
+	return p.maxAttempts
+}
+
+/**
+ * Sets how many dials a reconnect makes before giving up and failing the
+ * call with NOT_OPEN; the next call tries again.  Zero means no limit.
+ */
+func (p *TReconnectingTransport) SetMaxAttempts(maxAttempts int) {
+	p.maxAttempts = maxAttempts
+}
+
+func (p *TReconnectingTransport) InitialBackoff() time.Duration {
+	return p.initialBackoff
+}
+
+func (p *TReconnectingTransport) MaxBackoff() time.Duration {
+	return p.maxBackoff
+}
+
+/**
+ * Sets the wait before the second dial and the most it may grow to.  Waits
+ * shorter than MIN_RECONNECT_BACKOFF are raised to it, and maxBackoff to
+ * initialBackoff.
+ */
+func (p *TReconnectingTransport) SetBackoff(initialBackoff, maxBackoff time.Duration) {
+	if initialBackoff < MIN_RECONNECT_BACKOFF {
+		initialBackoff = MIN_RECONNECT_BACKOFF
+	}
+	if maxBackoff < initialBackoff {
+		maxBackoff = initialBackoff
+	}
+	p.initialBackoff = initialBackoff
+	p.maxBackoff = maxBackoff
+}
+
+func (p *TReconnectingTransport) SetReconnectFunc(onReconnect TReconnectFunc) {
+	p.onReconnect = onReconnect
+}
+
+func (p *TReconnectingTransport) Open() error {
+	return p.transport.Open()
+}
+
+func (p *TReconnectingTransport) IsOpen() bool {
+	return p.transport.IsOpen()
+}
+
+func (p *TReconnectingTransport) Peek() bool {
+	return p.transport.Peek()
+}
+
+func (p *TReconnectingTransport) Close() error {
+	return p.transport.Close()
+}
+
+func (p *TReconnectingTransport) Read(buf []byte) (int, error) {
+	n, err := p.transport.Read(buf)
+	return n, p.check(err)
+}
+
+func (p *TReconnectingTransport) ReadAll(buf []byte) (int, error) {
+	return ReadAllTransport(p, buf)
+}
+
+func (p *TReconnectingTransport) Write(buf []byte) (int, error) {
+	if !p.transport.IsOpen() {
+		if err := p.reconnect(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := p.transport.Write(buf)
+	return n, p.check(err)
+}
+
+func (p *TReconnectingTransport) Flush() error {
+	return p.check(p.transport.Flush())
+}
+
+func (p *TReconnectingTransport) SetDeadline(deadline time.Time) error {
+	return setTransportDeadline(p.transport, deadline)
+}
+
+/**
+ * Closes the socket after a failure, leaving whatever was in flight behind.
+ */
+func (p *TReconnectingTransport) check(err error) error {
+	if err != nil && p.transport.IsOpen() {
+		p.cause = err
+		p.transport.Close()
+	}
+	return err
+}
+
+func (p *TReconnectingTransport) reconnect() error {
+	backoff := p.initialBackoff
+	for attempts := 1; ; attempts++ {
+		err := p.transport.Open()
+		if err == nil {
+			cause := p.cause
+			p.cause = nil
+			if p.onReconnect != nil {
+				p.onReconnect(attempts, cause)
+			}
+			return nil
+		}
+		if p.maxAttempts > 0 && attempts >= p.maxAttempts {
+			return NewTTransportException(NOT_OPEN, "Unable to reconnect after "+strconv.Itoa(attempts)+" attempts: "+err.Error())
+		}
+		time.Sleep(backoff - time.Duration(rand.Int63n(int64(backoff)/2+1)))
+		if backoff *= 2; backoff > p.maxBackoff {
+			backoff = p.maxBackoff
+		}
+	}
+}
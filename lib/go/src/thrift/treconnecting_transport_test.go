@@ -0,0 +1,97 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReconnectingTransportSurvivesServerRestart(t *testing.T) {
+	server, addr := startTestServer(t)
+	trans := NewTReconnectingTransport(NewTSocket(addr, 5e9))
+	trans.SetBackoff(10*time.Millisecond, 100*time.Millisecond)
+	reconnects := 0
+	var cause error
+	trans.SetReconnectFunc(func(attempts int, err error) {
+		reconnects++
+		cause = err
+	})
+	if err := trans.Open(); err != nil {
+		t.Fatalf("Unable to open client socket: %s", err)
+	}
+	defer trans.Close()
+	prot := NewTBinaryProtocolTransport(trans)
+	if err := pingCall(prot); err != nil {
+		t.Fatalf("Expected the first call to succeed, but found %s", err)
+	}
+
+	server.Shutdown(5e9)
+	serverTransport, serr := NewTServerSocketAddrTimeout(addr, 5e9)
+	if serr != nil {
+		t.Fatalf("Unable to create server socket: %s", serr)
+	}
+	if err := serverTransport.Listen(); err != nil {
+		t.Fatalf("Unable to listen again on %s: %s", addr, err)
+	}
+	server = NewTNonblockingServer2(&recordingProcessor{}, serverTransport)
+	go server.Serve()
+	defer server.Stop()
+
+	// the connection the server dropped only shows up as broken on this call
+	if err := pingCall(prot); err == nil {
+		t.Fatalf("Expected the call over the dropped connection to fail")
+	}
+	if err := pingCall(prot); err != nil {
+		t.Fatalf("Expected the next call to reconnect, but found %s", err)
+	}
+	if reconnects != 1 || cause == nil {
+		t.Fatalf("Expected 1 reconnect caused by the failure, but found %d %v", reconnects, cause)
+	}
+}
+
+func TestReconnectingTransportGivesUp(t *testing.T) {
+	serverTransport, addr := listenForTest(t)
+	serverTransport.Close()
+	trans := NewTReconnectingTransport(NewTSocket(addr, 5e9))
+	trans.SetMaxAttempts(3)
+	trans.SetBackoff(time.Millisecond, 2*time.Millisecond)
+	reconnects := 0
+	trans.SetReconnectFunc(func(attempts int, err error) { reconnects++ })
+	_, err := trans.Write([]byte{0})
+	if e, ok := err.(TTransportException); !ok || e.TypeId() != NOT_OPEN {
+		t.Fatalf("Expected NOT_OPEN once the attempts ran out, but found %v", err)
+	}
+	if reconnects != 0 || trans.IsOpen() {
+		t.Fatalf("Expected the transport to stay closed")
+	}
+}
+
+func TestReconnectingTransportBackoffMinimum(t *testing.T) {
+	trans := NewTReconnectingTransport(NewTSocket(nil, 0))
+	trans.SetBackoff(0, 0)
+	if trans.InitialBackoff() != MIN_RECONNECT_BACKOFF || trans.MaxBackoff() != MIN_RECONNECT_BACKOFF {
+		t.Fatalf("Expected the backoff to be raised to %s, but found %s and %s", MIN_RECONNECT_BACKOFF, trans.InitialBackoff(), trans.MaxBackoff())
+	}
+	trans.SetBackoff(time.Second, time.Millisecond)
+	if trans.MaxBackoff() != time.Second {
+		t.Fatalf("Expected the maximum backoff to be raised to the initial one, but found %s", trans.MaxBackoff())
+	}
+}
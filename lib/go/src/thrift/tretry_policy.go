@@ -0,0 +1,224 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_RETRY_MAX_ATTEMPTS    = 3
+	DEFAULT_RETRY_INITIAL_BACKOFF = 50 * time.Millisecond
+)
+
+/**
+ * Which calls generated clients retry, and how.  Only methods listed in
+ * Idempotent, by their name in the IDL, are retried, and only after a
+ * TTransportException: an exception declared by the method, a
+ * TApplicationException or any other error means the server saw the call,
+ * so it is returned straight away.
+ *
+ * Before each retry the transport is closed and opened again, after a
+ * backoff that doubles from InitialBackoff up to MaxBackoff, with up to half
+ * of it taken off at random.  MaxAttempts counts the first attempt; zero
+ * means DEFAULT_RETRY_MAX_ATTEMPTS.  A Budget shared between clients stops
+ * them all from retrying while most calls fail.
+ *
+ * Clients generated with concurrent_client have no Retries field: once
+ * their connection fails every call on it fails, so they are not retried.
+ */
+type TRetryPolicy struct {
+	Idempotent     map[string]bool
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Budget         *TRetryBudget
+}
+
+/**
+ * Starts a call to method made over transport; see BeginContext.
+ */
+func (p TRetryPolicy) Begin(method string, transport TTransport) *TRetryState {
+	return p.BeginContext(context.Background(), method, transport)
+}
+
+/**
+ * Starts a call to method made over transport, which generated clients
+ * then attempt until Again returns false.  Retries stop once ctx is done.
+ * Returns nil, on which Again returns false, if method is not idempotent
+ * and there is no budget to account the call to.
+ */
+func (p TRetryPolicy) BeginContext(ctx context.Context, method string, transport TTransport) *TRetryState {
+	idempotent := p.Idempotent[method]
+	if !idempotent && p.Budget == nil {
+		return nil
+	}
+	maxAttempts := p.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = DEFAULT_RETRY_MAX_ATTEMPTS
+	}
+	backoff := p.InitialBackoff
+	if backoff == 0 {
+		backoff = DEFAULT_RETRY_INITIAL_BACKOFF
+	}
+	return &TRetryState{
+		ctx:         ctx,
+		transport:   transport,
+		idempotent:  idempotent,
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+		maxBackoff:  p.MaxBackoff,
+		budget:      p.Budget,
+		attempts:    1,
+	}
+}
+
+/**
+ * The progress of a call through its attempts.
+ */
+type TRetryState struct {
+	ctx         context.Context
+	transport   TTransport
+	idempotent  bool
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	budget      *TRetryBudget
+	attempts    int
+}
+
+/**
+ * Returns the number of attempts made so far.
+ */
+func (p *TRetryState) Attempts() int {
+	if p == nil {
+		return 1
+	}
+	return p.attempts
+}
+
+/**
+ * Takes err, the outcome of the last attempt, and reports whether the
+ * call should be attempted again.  When it should, the transport has been
+ * opened again after the backoff.  When it should not, err is the outcome
+ * of the call; a transport that cannot be opened again ends the call with
+ * the error of the last attempt.
+ */
+func (p *TRetryState) Again(err error) bool {
+	if p == nil {
+		return false
+	}
+	if e, ok := err.(TTransportException); ok && e.TypeId() == CIRCUIT_BREAKER_OPEN {
+		// the server is being left to recover
+		return false
+	}
+	if !isTransientError(err) {
+		p.budget.succeeded()
+		return false
+	}
+	p.budget.failed()
+	for p.idempotent && p.attempts < p.maxAttempts && p.budget.allow() {
+		p.transport.Close()
+		backoff := p.backoff - time.Duration(rand.Int63n(int64(p.backoff)/2+1))
+		select {
+		case <-time.After(backoff):
+		case <-p.ctx.Done():
+			return false
+		}
+		p.attempts++
+		if p.backoff *= 2; p.maxBackoff > 0 && p.backoff > p.maxBackoff {
+			p.backoff = p.maxBackoff
+		}
+		if p.transport.Open() == nil {
+			return true
+		}
+		p.budget.failed()
+	}
+	return false
+}
+
+/**
+ * Whether err is a failure of the connection rather than an answer from
+ * the server.
+ */
+func isTransientError(err error) bool {
+	_, ok := err.(TTransportException)
+	return ok
+}
+
+/**
+ * Limits retries across the clients sharing it, as a bucket of tokens that
+ * starts full: every failed attempt takes a token, every successful call
+ * puts back tokenRatio of one, and retries are only made while the bucket is
+ * more than half full.
+ */
+type TRetryBudget struct {
+	lock       sync.Mutex
+	tokens     float64
+	maxTokens  float64
+	tokenRatio float64
+}
+
+func NewTRetryBudget(maxTokens, tokenRatio float64) *TRetryBudget {
+	return &TRetryBudget{tokens: maxTokens, maxTokens: maxTokens, tokenRatio: tokenRatio}
+}
+
+/**
+ * Returns the tokens left in the bucket.
+ */
+func (p *TRetryBudget) Tokens() float64 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.tokens
+}
+
+func (p *TRetryBudget) allow() bool {
+	if p == nil {
+		return true
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.tokens > p.maxTokens/2
+}
+
+func (p *TRetryBudget) succeeded() {
+	if p == nil {
+		return
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.tokens += p.tokenRatio; p.tokens > p.maxTokens {
+		p.tokens = p.maxTokens
+	}
+}
+
+func (p *TRetryBudget) failed() {
+	if p == nil {
+		return
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.tokens--; p.tokens < 0 {
+		p.tokens = 0
+	}
+}
@@ -0,0 +1,115 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+/**
+ * Memory transport that counts how often it was opened.
+ */
+type openCountingTransport struct {
+	*TMemoryBuffer
+	opens int
+}
+
+func (p *openCountingTransport) Open() error {
+	p.opens++
+	return nil
+}
+
+/**
+ * Attempts a call the way generated clients do, with every attempt failing
+ * with err, and returns the number of attempts made.
+ */
+func attemptCall(policy TRetryPolicy, method string, trans TTransport, err error) int {
+	attempts := 0
+	for retry := policy.Begin(method, trans); ; {
+		attempts++
+		if !retry.Again(err) {
+			return attempts
+		}
+	}
+}
+
+func TestRetryPolicyRetriesIdempotentCalls(t *testing.T) {
+	trans := &openCountingTransport{TMemoryBuffer: NewTMemoryBuffer()}
+	policy := TRetryPolicy{Idempotent: map[string]bool{"get": true}, InitialBackoff: time.Millisecond}
+	timedOut := NewTTransportException(TIMED_OUT, "timed out")
+	if attempts := attemptCall(policy, "get", trans, timedOut); attempts != DEFAULT_RETRY_MAX_ATTEMPTS {
+		t.Fatalf("Expected %d attempts, but found %d", DEFAULT_RETRY_MAX_ATTEMPTS, attempts)
+	}
+	if trans.opens != DEFAULT_RETRY_MAX_ATTEMPTS-1 {
+		t.Fatalf("Expected the transport to be opened again before each retry, but found %d opens", trans.opens)
+	}
+	if attempts := attemptCall(policy, "set", trans, timedOut); attempts != 1 {
+		t.Fatalf("Expected a call that is not idempotent not to be retried, but found %d attempts", attempts)
+	}
+}
+
+func TestRetryPolicyRetriesOsErrors(t *testing.T) {
+	trans := &openCountingTransport{TMemoryBuffer: NewTMemoryBuffer()}
+	policy := TRetryPolicy{Idempotent: map[string]bool{"get": true}, InitialBackoff: time.Millisecond}
+	eof := NewTTransportExceptionFromOsError(io.EOF)
+	if eof.Err() != io.EOF {
+		t.Fatalf("Expected the transport exception to keep %v, but found %v", io.EOF, eof.Err())
+	}
+	if attempts := attemptCall(policy, "get", trans, eof); attempts != DEFAULT_RETRY_MAX_ATTEMPTS {
+		t.Fatalf("Expected %d attempts, but found %d", DEFAULT_RETRY_MAX_ATTEMPTS, attempts)
+	}
+}
+
+func TestRetryPolicyLeavesAnswersAlone(t *testing.T) {
+	trans := &openCountingTransport{TMemoryBuffer: NewTMemoryBuffer()}
+	policy := TRetryPolicy{Idempotent: map[string]bool{"get": true}, InitialBackoff: time.Millisecond}
+	for _, err := range []error{
+		nil,
+		errors.New("declared exception"),
+		NewTApplicationException(INTERNAL_ERROR, "handler failed"),
+		NewTProtocolException(INVALID_DATA, "bad reply"),
+	} {
+		if attempts := attemptCall(policy, "get", trans, err); attempts != 1 {
+			t.Fatalf("Expected %v not to be retried, but found %d attempts", err, attempts)
+		}
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	trans := &openCountingTransport{TMemoryBuffer: NewTMemoryBuffer()}
+	budget := NewTRetryBudget(4, 0.5)
+	policy := TRetryPolicy{Idempotent: map[string]bool{"get": true}, MaxAttempts: 10, InitialBackoff: time.Millisecond, Budget: budget}
+	notOpen := NewTTransportException(NOT_OPEN, "refused")
+	if attempts := attemptCall(policy, "get", trans, notOpen); attempts != 2 {
+		t.Fatalf("Expected retries to stop once half the budget was spent, but found %d attempts", attempts)
+	}
+	if attempts := attemptCall(policy, "get", trans, notOpen); attempts != 1 {
+		t.Fatalf("Expected no retries with the budget spent, but found %d attempts", attempts)
+	}
+	for i := 0; i < 4; i++ {
+		attemptCall(policy, "set", trans, nil)
+	}
+	if tokens := budget.Tokens(); tokens != 3 {
+		t.Fatalf("Expected successful calls to refill the budget to 3 tokens, but found %v", tokens)
+	}
+}
@@ -0,0 +1,334 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+/**
+ * Stopped flag and open connections of a server, shared by the server
+ * implementations so that Stop() and Shutdown() are safe to call from any
+ * goroutine.
+ */
+type tServerConnections struct {
+	lock        sync.Mutex
+	stopped     bool
+	connections map[*tServerConnection]bool
+	wg          sync.WaitGroup
+}
+
+/**
+ * An accepted connection.  It counts as busy from the moment the first byte
+ * of a message is read until the processor is done with that message, so
+ * that a shutdown only cuts off connections that are waiting for a request.
+ *
+ * The contexts of its requests derive from ctx, which carries the
+ * TConnectionInfo of the connection and is cancelled once the connection is
+ * closed by either side.
+ */
+type tServerConnection struct {
+	TTransport
+	lock     sync.Mutex
+	busy     bool
+	closing  bool
+	handling bool
+	ctx      context.Context
+	cancel   context.CancelFunc
+	// only touched by the goroutine serving the connection
+	ahead chan tReadAhead
+}
+
+/**
+ * The outcome of a one byte read made while a request was being handled.
+ */
+type tReadAhead struct {
+	b   byte
+	n   int
+	err error
+}
+
+func (p *tServerConnections) start() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.stopped = false
+}
+
+func (p *tServerConnections) stop() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.stopped = true
+}
+
+func (p *tServerConnections) isStopped() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.stopped
+}
+
+/**
+ * Registers client, returning nil if the server is no longer accepting.
+ */
+func (p *tServerConnections) add(client TTransport) *tServerConnection {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.stopped {
+		return nil
+	}
+	if p.connections == nil {
+		p.connections = make(map[*tServerConnection]bool)
+	}
+	conn := &tServerConnection{TTransport: client}
+	conn.ctx, conn.cancel = context.WithCancel(connectionContext(client))
+	p.connections[conn] = true
+	p.wg.Add(1)
+	return conn
+}
+
+func (p *tServerConnections) remove(conn *tServerConnection) {
+	conn.release()
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.connections[conn] {
+		delete(p.connections, conn)
+		p.wg.Done()
+	}
+}
+
+/**
+ * Marks the end of a message on conn, returning true if the connection
+ * should not wait for another one.
+ */
+func (p *tServerConnections) done(conn *tServerConnection) bool {
+	conn.lock.Lock()
+	conn.busy = false
+	closing := conn.closing
+	conn.lock.Unlock()
+	return closing || p.isStopped()
+}
+
+/**
+ * Stops accepting, closes idle connections and waits up to nsecTimeout for
+ * busy ones to finish their current message.  Connections still open at the
+ * deadline are closed and a TIMED_OUT TTransportException is returned.
+ */
+func (p *tServerConnections) drain(nsecTimeout int64) error {
+	p.lock.Lock()
+	p.stopped = true
+	for conn := range p.connections {
+		conn.closeIfIdle()
+	}
+	p.lock.Unlock()
+
+	drained := make(chan bool)
+	go func() {
+		p.wg.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return nil
+	case <-time.After(time.Duration(nsecTimeout)):
+	}
+	p.lock.Lock()
+	for conn := range p.connections {
+		conn.interrupt()
+	}
+	p.lock.Unlock()
+	return NewTTransportException(TIMED_OUT, "Shutdown timed out before all connections were drained")
+}
+
+/**
+ * A server whose connections are served by tServerConnections.serve.
+ */
+type tConnectionServer interface {
+	TServer
+	ServerEventHandler() TServerEventHandler
+	Logger() TLogger
+}
+
+/**
+ * Serves the requests on conn with the factories and handlers of server
+ * until the client hangs up, processing fails or the server stops, then
+ * closes conn.
+ */
+func (p *tServerConnections) serve(server tConnectionServer, conn *tServerConnection) {
+	client := conn.TTransport
+	processor := server.ProcessorFactory().GetProcessor(client)
+	inputTransport := server.InputTransportFactory().GetTransport(conn)
+	outputTransport := server.OutputTransportFactory().GetTransport(client)
+	inputProtocol := server.InputProtocolFactory().GetProtocol(inputTransport)
+	outputProtocol := server.OutputProtocolFactory().GetProtocol(outputTransport)
+	if headerProtocol, ok := inputProtocol.(*THeaderProtocol); ok {
+		// replies must go out in the dialect the request was read in
+		outputProtocol = headerProtocol
+	}
+	if inputTransport != nil {
+		defer inputTransport.Close()
+	}
+	if outputTransport != nil {
+		defer outputTransport.Close()
+	}
+	defer p.remove(conn)
+	eventHandler := server.ServerEventHandler()
+	var serverContext interface{}
+	if eventHandler != nil {
+		serverContext = eventHandler.CreateContext(inputProtocol, outputProtocol)
+		defer eventHandler.DeleteContext(serverContext, inputProtocol, outputProtocol)
+	}
+	for {
+		if eventHandler != nil {
+			eventHandler.ProcessContext(serverContext, client)
+		}
+		request, finished := conn.request(inputProtocol)
+		ok, e := ProcessWithRecovery(processor, request, outputProtocol, server.Logger())
+		finished()
+		if e != nil {
+			if !p.isStopped() {
+				// TODO(pomack) log error
+				break
+			}
+		}
+		if !ok || p.done(conn) {
+			break
+		}
+	}
+}
+
+/**
+ * Wraps in for processing the next request, returning the protocol to
+ * process it from and the function to call once it has been processed.
+ * The context of the request is available to the processor through
+ * RequestContext.
+ */
+func (p *tServerConnection) request(in TProtocol) (TProtocol, func()) {
+	ctx, cancel := context.WithCancel(p.ctx)
+	request := &tContextProtocol{TProtocol: in, ctx: ctx, watch: p.watch}
+	return request, func() {
+		p.lock.Lock()
+		p.handling = false
+		p.lock.Unlock()
+		cancel()
+	}
+}
+
+/**
+ * Reads ahead while the current request is being handled so that a client
+ * hanging up cancels the connection's context.  The byte read, if any, is
+ * handed to the next Read.
+ */
+func (p *tServerConnection) watch() {
+	p.lock.Lock()
+	p.handling = true
+	p.lock.Unlock()
+	if p.ahead != nil {
+		return
+	}
+	ahead := make(chan tReadAhead, 1)
+	p.ahead = ahead
+	go func() {
+		buf := make([]byte, 1)
+		var r tReadAhead
+		for {
+			p.lock.Lock()
+			handling := p.handling
+			p.lock.Unlock()
+			r.n, r.err = p.TTransport.Read(buf)
+			if te, ok := r.err.(TTransportException); ok && r.n == 0 && handling && te.TypeId() == TIMED_OUT {
+				// the timeout ran down while the handler was busy, so the
+				// client has not had the full timeout to send a request
+				continue
+			}
+			break
+		}
+		if r.n == 0 && r.err != nil {
+			p.cancel()
+		}
+		r.b = buf[0]
+		ahead <- r
+	}()
+}
+
+/**
+ * Cancels the connection's context and waits for a read ahead to finish so
+ * that the transport can be closed.
+ */
+func (p *tServerConnection) release() {
+	p.cancel()
+	if p.ahead != nil {
+		interruptTransport(p.TTransport)
+		<-p.ahead
+		p.ahead = nil
+	}
+}
+
+func (p *tServerConnection) Read(buf []byte) (int, error) {
+	var n int
+	var err error
+	if p.ahead != nil && len(buf) > 0 {
+		r := <-p.ahead
+		p.ahead = nil
+		buf[0] = r.b
+		n, err = r.n, r.err
+	} else {
+		n, err = p.TTransport.Read(buf)
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.closing {
+		return 0, NewTTransportException(NOT_OPEN, "Connection closed by server shutdown")
+	}
+	if n > 0 {
+		p.busy = true
+	}
+	return n, err
+}
+
+func (p *tServerConnection) ReadAll(buf []byte) (int, error) {
+	return ReadAllTransport(p, buf)
+}
+
+func (p *tServerConnection) closeIfIdle() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if !p.busy {
+		p.closing = true
+		p.cancel()
+		p.interruptLocked()
+	}
+}
+
+func (p *tServerConnection) interrupt() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.closing = true
+	p.cancel()
+	p.interruptLocked()
+}
+
+/**
+ * Unblocks a pending Read.  The serving goroutine still owns the
+ * connection and closes it on its way out.
+ */
+func (p *tServerConnection) interruptLocked() {
+	interruptTransport(p.TTransport)
+}
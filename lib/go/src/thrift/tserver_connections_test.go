@@ -0,0 +1,124 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"testing"
+	"time"
+)
+
+type blockingProcessor struct {
+	entered chan bool
+	release chan bool
+}
+
+func newBlockingProcessor() *blockingProcessor {
+	return &blockingProcessor{entered: make(chan bool, 1), release: make(chan bool)}
+}
+
+func (p *blockingProcessor) Process(in, out TProtocol) (bool, TException) {
+	name, _, seqid, err := in.ReadMessageBegin()
+	if err != nil {
+		return false, err
+	}
+	in.Skip(STRUCT)
+	in.ReadMessageEnd()
+	p.entered <- true
+	<-p.release
+	out.WriteMessageBegin(name, REPLY, seqid)
+	out.WriteStructBegin("result")
+	out.WriteFieldStop()
+	out.WriteStructEnd()
+	out.WriteMessageEnd()
+	return true, out.Flush()
+}
+
+func TestNonblockingServerShutdownDrainsBusyConnection(t *testing.T) {
+	serverTransport, addr := listenForTest(t)
+	processor := newBlockingProcessor()
+	server := NewTNonblockingServer2(processor, serverTransport)
+	go server.Serve()
+
+	trans, prot := openTestClient(t, addr)
+	defer trans.Close()
+	writeEmptyCall(t, prot, "slow", 3)
+	<-processor.entered
+
+	shutdown := make(chan error)
+	go func() {
+		shutdown <- server.Shutdown(5e9)
+	}()
+	select {
+	case err := <-shutdown:
+		t.Fatalf("Expected Shutdown to wait for the call in progress, but it returned %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	processor.release <- true
+	_, typeId, seqid, err := prot.ReadMessageBegin()
+	if err != nil || typeId != REPLY || seqid != 3 {
+		t.Fatalf("Expected REPLY with seqid 3, but found %d %d %v", typeId, seqid, err)
+	}
+	if err := <-shutdown; err != nil {
+		t.Fatalf("Expected connections to drain, but found %s", err)
+	}
+	if !server.IsStopped() {
+		t.Fatalf("Expected server to be stopped after Shutdown")
+	}
+}
+
+func TestSimpleServerShutdownClosesIdleConnection(t *testing.T) {
+	serverTransport, addr := listenForTest(t)
+	server := NewTSimpleServer2(&recordingProcessor{}, serverTransport)
+	go server.Serve()
+
+	trans, prot := openTestClient(t, addr)
+	defer trans.Close()
+	writeEmptyCall(t, prot, "ping", 1)
+	if _, typeId, _, err := prot.ReadMessageBegin(); err != nil || typeId != REPLY {
+		t.Fatalf("Expected REPLY, but found %d %v", typeId, err)
+	}
+	prot.Skip(STRUCT)
+	prot.ReadMessageEnd()
+
+	if err := server.Shutdown(5e9); err != nil {
+		t.Fatalf("Expected idle connection to be closed, but found %s", err)
+	}
+	if _, _, _, err := prot.ReadMessageBegin(); err == nil {
+		t.Fatalf("Expected the idle connection to be closed by Shutdown")
+	}
+}
+
+func TestServerShutdownTimesOut(t *testing.T) {
+	serverTransport, addr := listenForTest(t)
+	processor := newBlockingProcessor()
+	server := NewTNonblockingServer2(processor, serverTransport)
+	go server.Serve()
+
+	trans, prot := openTestClient(t, addr)
+	defer trans.Close()
+	writeEmptyCall(t, prot, "stuck", 4)
+	<-processor.entered
+	defer close(processor.release)
+
+	err := server.Shutdown(int64(50 * time.Millisecond))
+	if e, ok := err.(TTransportException); !ok || e.TypeId() != TIMED_OUT {
+		t.Fatalf("Expected TIMED_OUT, but found %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import ()
+
+/**
+ * Interface that can handle events from the server core. To
+ * use this you should subclass it and implement the methods that you care
+ * about. Your subclass can also store local data that you may care about,
+ * such as additional "arguments" to these methods (stored in the object
+ * instance's state).
+ *
+ * Servers may call the connection hooks from several goroutines at once.
+ */
+type TServerEventHandler interface {
+	/**
+	 * Called before the server begins accepting connections.
+	 */
+	PreServe()
+	/**
+	 * Called when a new client has connected and is about to begin
+	 * processing.  The returned value is handed back to ProcessContext and
+	 * DeleteContext for that connection.
+	 */
+	CreateContext(input, output TProtocol) interface{}
+	/**
+	 * Called before a request is read off the connection, with the
+	 * transport the client connected on.
+	 */
+	ProcessContext(serverContext interface{}, transport TTransport)
+	/**
+	 * Called when a client has finished request-handling to delete server
+	 * context.
+	 */
+	DeleteContext(serverContext interface{}, input, output TProtocol)
+}
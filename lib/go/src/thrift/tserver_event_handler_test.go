@@ -0,0 +1,98 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"sync"
+	"testing"
+)
+
+type countingEventHandler struct {
+	lock      sync.Mutex
+	preServe  int
+	created   int
+	processed map[interface{}]int
+	peer      string
+	deleted   chan interface{}
+}
+
+func (p *countingEventHandler) PreServe() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.preServe++
+}
+
+func (p *countingEventHandler) CreateContext(input, output TProtocol) interface{} {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.created++
+	return p.created
+}
+
+func (p *countingEventHandler) ProcessContext(serverContext interface{}, transport TTransport) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.processed[serverContext]++
+	if socket, ok := transport.(*TSocket); ok {
+		p.peer = socket.Conn().RemoteAddr().String()
+	}
+}
+
+func (p *countingEventHandler) DeleteContext(serverContext interface{}, input, output TProtocol) {
+	p.deleted <- serverContext
+}
+
+func TestServerEventHandler(t *testing.T) {
+	serverTransport, addr := listenForTest(t)
+	server := NewTSimpleServer2(&recordingProcessor{}, serverTransport)
+	handler := &countingEventHandler{processed: make(map[interface{}]int), deleted: make(chan interface{}, 1)}
+	server.SetServerEventHandler(handler)
+	go server.Serve()
+	defer server.Stop()
+
+	trans, prot := openTestClient(t, addr)
+	for i := int32(1); i <= 2; i++ {
+		writeEmptyCall(t, prot, "ping", i)
+		if _, typeId, _, err := prot.ReadMessageBegin(); err != nil || typeId != REPLY {
+			t.Fatalf("Expected REPLY, but found %d %v", typeId, err)
+		}
+		prot.Skip(STRUCT)
+		prot.ReadMessageEnd()
+	}
+	local := trans.Conn().LocalAddr().String()
+	trans.Close()
+
+	serverContext := <-handler.deleted
+	handler.lock.Lock()
+	defer handler.lock.Unlock()
+	if handler.preServe != 1 || handler.created != 1 {
+		t.Fatalf("Expected 1 PreServe and 1 CreateContext, but found %d and %d", handler.preServe, handler.created)
+	}
+	if serverContext != 1 {
+		t.Fatalf("Expected DeleteContext with the created context 1, but found %v", serverContext)
+	}
+	// once before each call and once more before the read that sees the close
+	if handler.processed[serverContext] != 3 {
+		t.Fatalf("Expected 3 ProcessContext calls, but found %d", handler.processed[serverContext])
+	}
+	if handler.peer != local {
+		t.Fatalf("Expected peer address %s, but found %s", local, handler.peer)
+	}
+}
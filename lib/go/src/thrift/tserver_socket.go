@@ -85,7 +85,7 @@ func NewTServerSocketAddrTimeout(addr net.Addr, nsecClientTimeout int64) (*TServ
 
 func (p *TServerSocket) Listen() (err error) {
 	if p.listener == nil {
-		if p.listener, err = net.Listen("tcp", p.addr.String()); err != nil {
+		if p.listener, err = net.Listen(p.addr.Network(), p.addr.String()); err != nil {
 			return err
 		}
 	}
@@ -97,11 +97,20 @@ func (p *TServerSocket) Accept() (TTransport, error) {
 		if err := p.Listen(); err != nil {
 			return nil, NewTTransportExceptionFromOsError(err)
 		}
-		if p.listener == nil {
-			return nil, NewTTransportException(NOT_OPEN, "No underlying server socket")
-		}
 	}
-	conn, err := p.listener.Accept()
+	return p.acceptListening()
+}
+
+/**
+ * Accepts a connection on the listener already set up, for the server
+ * sockets built on TServerSocket that set it up their own way.
+ */
+func (p *TServerSocket) acceptListening() (TTransport, error) {
+	listener := p.listener
+	if listener == nil {
+		return nil, NewTTransportException(NOT_OPEN, "No underlying server socket")
+	}
+	conn, err := listener.Accept()
 	if err != nil {
 		return nil, NewTTransportExceptionFromOsError(err)
 	}
@@ -24,7 +24,7 @@ package thrift
  *
  */
 type TSimpleServer struct {
-	stopped bool
+	connections tServerConnections
 
 	processorFactory       TProcessorFactory
 	serverTransport        TServerTransport
@@ -32,6 +32,8 @@ type TSimpleServer struct {
 	outputTransportFactory TTransportFactory
 	inputProtocolFactory   TProtocolFactory
 	outputProtocolFactory  TProtocolFactory
+	eventHandler           TServerEventHandler
+	logger                 TLogger
 }
 
 func NewTSimpleServer2(processor TProcessor, serverTransport TServerTransport) *TSimpleServer {
@@ -110,52 +112,71 @@ func (p *TSimpleServer) OutputProtocolFactory() TProtocolFactory {
 	return p.outputProtocolFactory
 }
 
+func (p *TSimpleServer) ServerEventHandler() TServerEventHandler {
+	return p.eventHandler
+}
+
+/**
+ * Sets the handler notified of server and connection events.  Must be called
+ * before Serve().
+ */
+func (p *TSimpleServer) SetServerEventHandler(eventHandler TServerEventHandler) {
+	p.eventHandler = eventHandler
+}
+
+func (p *TSimpleServer) Logger() TLogger {
+	return p.logger
+}
+
+/**
+ * Sets where panics recovered while processing are logged; LOGGER if nil.
+ */
+func (p *TSimpleServer) SetLogger(logger TLogger) {
+	p.logger = logger
+}
+
 func (p *TSimpleServer) Serve() error {
-	p.stopped = false
+	p.connections.start()
 	err := p.serverTransport.Listen()
 	if err != nil {
 		return err
 	}
-	for !p.stopped {
+	if p.eventHandler != nil {
+		p.eventHandler.PreServe()
+	}
+	for !p.connections.isStopped() {
 		client, err := p.serverTransport.Accept()
 		if err != nil {
+			if p.connections.isStopped() {
+				return nil
+			}
 			return err
 		}
-		if client != nil {
-			p.processRequest(client)
+		if client == nil {
+			continue
+		}
+		if conn := p.connections.add(client); conn != nil {
+			p.connections.serve(p, conn)
+		} else {
+			client.Close()
 		}
 	}
 	return nil
 }
 
 func (p *TSimpleServer) Stop() error {
-	p.stopped = true
+	p.connections.stop()
 	p.serverTransport.Interrupt()
 	return nil
 }
 
-func (p *TSimpleServer) processRequest(client TTransport) {
-	processor := p.processorFactory.GetProcessor(client)
-	inputTransport := p.inputTransportFactory.GetTransport(client)
-	outputTransport := p.outputTransportFactory.GetTransport(client)
-	inputProtocol := p.inputProtocolFactory.GetProtocol(inputTransport)
-	outputProtocol := p.outputProtocolFactory.GetProtocol(outputTransport)
-	if inputTransport != nil {
-		defer inputTransport.Close()
-	}
-	if outputTransport != nil {
-		defer outputTransport.Close()
-	}
-	for {
-		ok, e := processor.Process(inputProtocol, outputProtocol)
-		if e != nil {
-			if !p.stopped {
-				// TODO(pomack) log error
-				break
-			}
-		}
-		if !ok {
-			break
-		}
-	}
+/**
+ * Stops accepting connections and waits up to nsecTimeout for the
+ * connections being served to finish their current message.  Idle
+ * connections are closed straight away.
+ */
+func (p *TSimpleServer) Shutdown(nsecTimeout int64) error {
+	p.connections.stop()
+	p.serverTransport.Interrupt()
+	return p.connections.drain(nsecTimeout)
 }
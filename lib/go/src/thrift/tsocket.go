@@ -22,6 +22,7 @@ package thrift
 import (
 	"bytes"
 	"net"
+	"sync"
 	"time"
 )
 
@@ -43,6 +44,18 @@ type TSocket struct {
 	 * Socket timeout in nanoseconds
 	 */
 	nsecTimeout int64
+	/**
+	 * Absolute deadline set through SetDeadline.  lock guards it and the
+	 * replacing of conn against SetDeadline and Interrupt, which may be
+	 * called from other goroutines
+	 */
+	lock     sync.Mutex
+	deadline time.Time
+	/**
+	 * Set once Interrupt has closed conn, so that Close does not close it
+	 * again
+	 */
+	interrupted bool
 }
 
 /**
@@ -94,6 +107,17 @@ func NewTSocket(address net.Addr, nsecTimeout int64) *TSocket {
 	return sock
 }
 
+/**
+ * Creates a new unconnected socket that will connect to the Unix domain
+ * socket at path.
+ *
+ * @param path        Path of the socket file
+ * @param nsecTimeout Socket timeout
+ */
+func NewTUnixSocket(path string, nsecTimeout int64) *TSocket {
+	return NewTSocket(&net.UnixAddr{Name: path, Net: "unix"}, nsecTimeout)
+}
+
 /**
  * Sets the socket timeout
  *
@@ -104,11 +128,35 @@ func (p *TSocket) SetTimeout(nsecTimeout int64) error {
 	return nil
 }
 
+/**
+ * Sets an absolute deadline for reads and writes, on top of the timeout.  A
+ * zero time clears it.  May be called while another goroutine is blocked in
+ * Read, which then returns a TIMED_OUT error once the deadline has passed.
+ */
+func (p *TSocket) SetDeadline(deadline time.Time) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.deadline = deadline
+	if p.conn != nil {
+		p.pushDeadlineLocked(true, true)
+	}
+	return nil
+}
+
 func (p *TSocket) pushDeadline(read, write bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.pushDeadlineLocked(read, write)
+}
+
+func (p *TSocket) pushDeadlineLocked(read, write bool) {
 	var t time.Time
 	if p.nsecTimeout > 0 {
 		t = time.Now().Add(time.Duration(p.nsecTimeout))
 	}
+	if !p.deadline.IsZero() && (t.IsZero() || p.deadline.Before(t)) {
+		t = p.deadline
+	}
 	if read && write {
 		p.conn.SetDeadline(t)
 	} else if read {
@@ -151,33 +199,49 @@ func (p *TSocket) Open() error {
 	if len(p.addr.String()) == 0 {
 		return NewTTransportException(NOT_OPEN, "Cannot open bad address.")
 	}
+	var conn net.Conn
 	var err error
 	if p.nsecTimeout > 0 {
-		if p.conn, err = net.DialTimeout(p.addr.Network(), p.addr.String(), time.Duration(p.nsecTimeout)); err != nil {
+		if conn, err = net.DialTimeout(p.addr.Network(), p.addr.String(), time.Duration(p.nsecTimeout)); err != nil {
 			LOGGER.Print("Could not open socket", err.Error())
 			return NewTTransportException(NOT_OPEN, err.Error())
 		}
 	} else {
-		if p.conn, err = net.Dial(p.addr.Network(), p.addr.String()); err != nil {
+		if conn, err = net.Dial(p.addr.Network(), p.addr.String()); err != nil {
 			LOGGER.Print("Could not open socket", err.Error())
 			return NewTTransportException(NOT_OPEN, err.Error())
 		}
 	}
+	p.setConn(conn)
 	return nil
 }
 
+func (p *TSocket) setConn(conn net.Conn) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.conn = conn
+}
+
 /**
  * Closes the socket.
  */
 func (p *TSocket) Close() error {
-	// Close the socket
-	if p.conn != nil {
-		err := p.conn.Close()
-		if err != nil {
+	// Close the socket, dropping it even if closing failed so that the
+	// socket can be opened again
+	p.writeBuffer.Reset()
+	p.lock.Lock()
+	conn := p.conn
+	if p.interrupted {
+		conn = nil
+	}
+	p.conn = nil
+	p.interrupted = false
+	p.lock.Unlock()
+	if conn != nil {
+		if err := conn.Close(); err != nil {
 			LOGGER.Print("Could not close socket. ", err.Error())
 			return err
 		}
-		p.conn = nil
 	}
 	return nil
 }
@@ -204,8 +268,16 @@ func (p *TSocket) Write(buf []byte) (int, error) {
 	return len(buf), nil
 }
 
+/**
+ * Reports whether the socket is open and the server has not hung up, which
+ * can only be found out without reading on Unix; elsewhere it is the same
+ * as IsOpen.
+ */
 func (p *TSocket) Peek() bool {
-	return p.IsOpen()
+	p.lock.Lock()
+	conn := p.conn
+	p.lock.Unlock()
+	return conn != nil && peekConn(conn)
 }
 
 func (p *TSocket) Flush() error {
@@ -217,9 +289,12 @@ func (p *TSocket) Flush() error {
 }
 
 func (p *TSocket) Interrupt() error {
-	if !p.IsOpen() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.conn == nil || p.interrupted {
 		return nil
 	}
 	// TODO(pomack) fix Interrupt as this is probably wrong
+	p.interrupted = true
 	return p.conn.Close()
 }
@@ -0,0 +1,110 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"crypto/tls"
+	"net"
+)
+
+/**
+ * TServerSocket speaking TLS, handing out the accepted connections as
+ * TSockets.  The tls.Config must hold the certificate of the server
+ * (Certificates or GetCertificate).  To verify the certificates of clients,
+ * set ClientAuth, tls.RequireAndVerifyClientCert for instance, and the CAs
+ * they must be signed by in ClientCAs.  MinVersion defaults to
+ * DEFAULT_SSL_MIN_VERSION.
+ *
+ * The handshake with a client is made on the first read or write of its
+ * connection, so that a slow client does not hold up Accept; a client that
+ * fails it gets its connection closed like any other failed connection.
+ */
+type TSSLServerSocket struct {
+	*TServerSocket
+	cfg *tls.Config
+}
+
+type TSSLServerSocketTransportFactory struct {
+	addr              net.Addr
+	cfg               *tls.Config
+	nsecClientTimeout int64
+}
+
+func (p *TSSLServerSocketTransportFactory) GetTransport(trans TTransport) TTransport {
+	if trans != nil {
+		t, ok := trans.(*TSSLServerSocket)
+		if ok && t.addr != nil {
+			s, _ := NewTSSLServerSocketAddrTimeout(t.addr, t.cfg, t.nsecClientTimeout)
+			return s
+		}
+	}
+	s, _ := NewTSSLServerSocketAddrTimeout(p.addr, p.cfg, p.nsecClientTimeout)
+	return s
+}
+
+func NewTSSLServerSocketTransportFactory(addr net.Addr, cfg *tls.Config, nsecClientTimeout int64) *TSSLServerSocketTransportFactory {
+	return &TSSLServerSocketTransportFactory{addr: addr, cfg: cfg, nsecClientTimeout: nsecClientTimeout}
+}
+
+func NewTSSLServerSocketAddr(addr net.Addr, cfg *tls.Config) (*TSSLServerSocket, TTransportException) {
+	return NewTSSLServerSocketAddrTimeout(addr, cfg, 0)
+}
+
+func NewTSSLServerSocketAddrTimeout(addr net.Addr, cfg *tls.Config, nsecClientTimeout int64) (*TSSLServerSocket, TTransportException) {
+	s, err := NewTServerSocketAddrTimeout(addr, nsecClientTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &TSSLServerSocket{TServerSocket: s, cfg: sslConfig(cfg)}, nil
+}
+
+func (p *TSSLServerSocket) Listen() error {
+	if p.listener != nil {
+		return nil
+	}
+	if len(p.cfg.Certificates) == 0 && p.cfg.GetCertificate == nil && p.cfg.GetConfigForClient == nil {
+		return NewTTransportException(NOT_OPEN, "No certificate to serve TLS with")
+	}
+	l, err := net.Listen(p.addr.Network(), p.addr.String())
+	if err != nil {
+		return err
+	}
+	p.listener = tls.NewListener(l, p.cfg)
+	return nil
+}
+
+/**
+ * Starts listening.
+ */
+func (p *TSSLServerSocket) Open() error {
+	if p.IsOpen() {
+		return NewTTransportException(ALREADY_OPEN, "Server socket already open")
+	}
+	return p.Listen()
+}
+
+func (p *TSSLServerSocket) Accept() (TTransport, error) {
+	if p.listener == nil {
+		if err := p.Listen(); err != nil {
+			return nil, NewTTransportExceptionFromOsError(err)
+		}
+	}
+	return p.acceptListening()
+}
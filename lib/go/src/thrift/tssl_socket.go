@@ -0,0 +1,135 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"crypto/tls"
+	"net"
+	"time"
+)
+
+/**
+ * Oldest TLS version spoken when a configuration leaves MinVersion unset.
+ */
+const DEFAULT_SSL_MIN_VERSION = tls.VersionTLS12
+
+/**
+ * TSocket speaking TLS.  The tls.Config sets the certificate presented to
+ * servers asking for one (Certificates), the CAs the server certificate must
+ * be signed by (RootCAs, the system pool if nil), the name it must be valid
+ * for (ServerName, the host of the address if empty) and the oldest TLS
+ * version accepted (MinVersion, DEFAULT_SSL_MIN_VERSION if unset).  The
+ * handshake is made by Open, which fails with NOT_OPEN if the server
+ * certificate cannot be verified.
+ */
+type TSSLSocket struct {
+	*TSocket
+	cfg *tls.Config
+}
+
+type TSSLSocketTransportFactory struct {
+	addr        net.Addr
+	cfg         *tls.Config
+	nsecTimeout int64
+}
+
+func (p *TSSLSocketTransportFactory) GetTransport(trans TTransport) TTransport {
+	if trans != nil {
+		t, ok := trans.(*TSSLSocket)
+		if ok {
+			return NewTSSLSocket(t.addr, t.cfg, t.nsecTimeout)
+		}
+	}
+	return NewTSSLSocket(p.addr, p.cfg, p.nsecTimeout)
+}
+
+func NewTSSLSocketTransportFactory(addr net.Addr, cfg *tls.Config, nsecTimeout int64) *TSSLSocketTransportFactory {
+	return &TSSLSocketTransportFactory{addr: addr, cfg: cfg, nsecTimeout: nsecTimeout}
+}
+
+/**
+ * Creates a new unconnected socket that will connect to the given address
+ * with TLS.
+ */
+func NewTSSLSocketAddr(address net.Addr, cfg *tls.Config) *TSSLSocket {
+	return NewTSSLSocket(address, cfg, 0)
+}
+
+/**
+ * Creates a new unconnected socket that will connect to the given address
+ * with TLS, with nsecTimeout bounding the dial and handshake as well as
+ * every read and write.
+ */
+func NewTSSLSocket(address net.Addr, cfg *tls.Config, nsecTimeout int64) *TSSLSocket {
+	return &TSSLSocket{TSocket: NewTSocket(address, nsecTimeout), cfg: sslConfig(cfg)}
+}
+
+/**
+ * Connects the socket and makes the TLS handshake.
+ */
+func (p *TSSLSocket) Open() error {
+	if p.IsOpen() {
+		return NewTTransportException(ALREADY_OPEN, "Socket already connected.")
+	}
+	if p.addr == nil {
+		return NewTTransportException(NOT_OPEN, "Cannot open nil address.")
+	}
+	if len(p.addr.Network()) == 0 {
+		return NewTTransportException(NOT_OPEN, "Cannot open bad network name.")
+	}
+	if len(p.addr.String()) == 0 {
+		return NewTTransportException(NOT_OPEN, "Cannot open bad address.")
+	}
+	dialer := &net.Dialer{Timeout: time.Duration(p.nsecTimeout)}
+	conn, err := tls.DialWithDialer(dialer, p.addr.Network(), p.addr.String(), p.cfg)
+	if err != nil {
+		LOGGER.Print("Could not open TLS socket", err.Error())
+		return NewTTransportException(NOT_OPEN, err.Error())
+	}
+	p.setConn(conn)
+	return nil
+}
+
+/**
+ * Returns the state of the TLS connection, such as the negotiated version
+ * and the certificates presented by the server.  Only meaningful while the
+ * socket is open.
+ */
+func (p *TSSLSocket) ConnectionState() tls.ConnectionState {
+	if conn, ok := p.conn.(*tls.Conn); ok {
+		return conn.ConnectionState()
+	}
+	return tls.ConnectionState{}
+}
+
+/**
+ * Returns a copy of cfg, which may be nil, with the defaults filled in.
+ */
+func sslConfig(cfg *tls.Config) *tls.Config {
+	if cfg == nil {
+		cfg = &tls.Config{}
+	} else {
+		cfg = cfg.Clone()
+	}
+	if cfg.MinVersion == 0 {
+		cfg.MinVersion = DEFAULT_SSL_MIN_VERSION
+	}
+	return cfg
+}
@@ -0,0 +1,186 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+type testCertificates struct {
+	pool   *x509.CertPool
+	server tls.Certificate
+	client tls.Certificate
+}
+
+func newTestCertificate(t *testing.T, template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (tls.Certificate, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Unable to generate key: %s", err)
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("Unable to create certificate: %s", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Unable to parse certificate: %s", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}, cert
+}
+
+/**
+ * Makes a CA and the server and client certificates it signs.
+ */
+func newTestCertificates(t *testing.T) *testCertificates {
+	notBefore := time.Now().Add(-time.Hour)
+	notAfter := notBefore.Add(24 * time.Hour)
+	caCert, ca := newTestCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Thrift Test CA"},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+	caKey := caCert.PrivateKey.(*ecdsa.PrivateKey)
+	certs := &testCertificates{pool: x509.NewCertPool()}
+	certs.pool.AddCert(ca)
+	certs.server, _ = newTestCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:     []string{"localhost"},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca, caKey)
+	certs.client, _ = newTestCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca, caKey)
+	return certs
+}
+
+func startSSLTestServer(t *testing.T, cfg *tls.Config) (*TSimpleServer, net.Addr) {
+	addr, err := FindAvailableTCPServerPort(40000)
+	if err != nil {
+		t.Fatalf("Unable to find available tcp port addr: %s", err)
+	}
+	serverTransport, err := NewTSSLServerSocketAddrTimeout(addr, cfg, 5e9)
+	if err != nil {
+		t.Fatalf("Unable to create server socket: %s", err)
+	}
+	if err := serverTransport.Listen(); err != nil {
+		t.Fatalf("Unable to listen on %s: %s", addr, err)
+	}
+	server := NewTSimpleServer2(&recordingProcessor{}, serverTransport)
+	go server.Serve()
+	return server, addr
+}
+
+func TestSSLSocketCallsServer(t *testing.T) {
+	certs := newTestCertificates(t)
+	server, addr := startSSLTestServer(t, &tls.Config{Certificates: []tls.Certificate{certs.server}})
+	defer server.Stop()
+
+	trans := NewTSSLSocket(addr, &tls.Config{RootCAs: certs.pool}, 5e9)
+	if err := trans.Open(); err != nil {
+		t.Fatalf("Unable to open TLS socket: %s", err)
+	}
+	defer trans.Close()
+	if err := pingCall(NewTBinaryProtocolTransport(trans)); err != nil {
+		t.Fatalf("Unable to call over TLS: %s", err)
+	}
+	if version := trans.ConnectionState().Version; version < tls.VersionTLS12 {
+		t.Fatalf("Expected at least TLS 1.2, but negotiated %x", version)
+	}
+}
+
+func TestSSLSocketRejectsUnknownServer(t *testing.T) {
+	certs := newTestCertificates(t)
+	server, addr := startSSLTestServer(t, &tls.Config{Certificates: []tls.Certificate{certs.server}})
+	defer server.Stop()
+
+	trans := NewTSSLSocket(addr, &tls.Config{RootCAs: x509.NewCertPool()}, 5e9)
+	err := trans.Open()
+	if e, ok := err.(TTransportException); !ok || e.TypeId() != NOT_OPEN {
+		t.Fatalf("Expected NOT_OPEN for an untrusted server, but found %v", err)
+	}
+	if trans.IsOpen() {
+		t.Fatalf("Expected the socket to stay closed")
+	}
+}
+
+func TestSSLServerSocketVerifiesClients(t *testing.T) {
+	certs := newTestCertificates(t)
+	server, addr := startSSLTestServer(t, &tls.Config{
+		Certificates: []tls.Certificate{certs.server},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    certs.pool,
+	})
+	defer server.Stop()
+
+	anonymous := NewTSSLSocket(addr, &tls.Config{RootCAs: certs.pool}, 5e9)
+	if err := anonymous.Open(); err == nil {
+		if err := pingCall(NewTBinaryProtocolTransport(anonymous)); err == nil {
+			t.Fatalf("Expected a client without a certificate to be refused")
+		}
+		anonymous.Close()
+	}
+
+	trans := NewTSSLSocket(addr, &tls.Config{RootCAs: certs.pool, Certificates: []tls.Certificate{certs.client}}, 5e9)
+	if err := trans.Open(); err != nil {
+		t.Fatalf("Unable to open TLS socket: %s", err)
+	}
+	defer trans.Close()
+	if err := pingCall(NewTBinaryProtocolTransport(trans)); err != nil {
+		t.Fatalf("Unable to call with a client certificate: %s", err)
+	}
+}
+
+func TestSSLServerSocketNeedsCertificate(t *testing.T) {
+	addr, err := FindAvailableTCPServerPort(40000)
+	if err != nil {
+		t.Fatalf("Unable to find available tcp port addr: %s", err)
+	}
+	serverTransport, _ := NewTSSLServerSocketAddr(addr, nil)
+	if err := serverTransport.Listen(); err == nil {
+		serverTransport.Close()
+		t.Fatalf("Expected listening without a certificate to fail")
+	}
+}
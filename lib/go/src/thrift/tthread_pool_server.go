@@ -0,0 +1,430 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_THREAD_POOL_MAX_WORKERS = 5
+	DEFAULT_THREAD_POOL_MAX_QUEUED  = 0
+	// how long a rejected client has to send the request answered with an
+	// error under SATURATION_REJECT_EXCEPTION
+	THREAD_POOL_REJECT_TIMEOUT = time.Second
+	// how many rejected clients may be waited on at once under
+	// SATURATION_REJECT_EXCEPTION
+	THREAD_POOL_MAX_REJECTING = 16
+)
+
+/**
+ * What a TThreadPoolServer does with a newly accepted connection when all of
+ * its workers are busy and its accept queue is full.
+ */
+type TSaturationPolicy int
+
+const (
+	/**
+	 * Stop accepting until a worker or a queue slot frees up.
+	 */
+	SATURATION_BLOCK TSaturationPolicy = iota
+	/**
+	 * Close the connection without reading from it.
+	 */
+	SATURATION_REJECT_CLOSE
+	/**
+	 * Read the first request off the connection, answer it with an
+	 * INTERNAL_ERROR TApplicationException and close the connection.  The
+	 * client gets THREAD_POOL_REJECT_TIMEOUT to send its request.  Once
+	 * THREAD_POOL_MAX_REJECTING clients are being answered, further ones are
+	 * closed as under SATURATION_REJECT_CLOSE.
+	 */
+	SATURATION_REJECT_EXCEPTION
+)
+
+/**
+ * Server which serves each connection on one of a fixed number of worker
+ * goroutines.  Accepted connections wait in a bounded queue until a worker is
+ * free; once the queue is full the saturation policy decides what happens to
+ * further connections.  Connections still queued or waiting to be rejected
+ * when the server is stopped are closed without being served.
+ */
+type TThreadPoolServer struct {
+	connections tServerConnections
+	lock        sync.Mutex
+
+	processorFactory       TProcessorFactory
+	serverTransport        TServerTransport
+	inputTransportFactory  TTransportFactory
+	outputTransportFactory TTransportFactory
+	inputProtocolFactory   TProtocolFactory
+	outputProtocolFactory  TProtocolFactory
+	eventHandler           TServerEventHandler
+	logger                 TLogger
+
+	maxWorkers       int
+	maxQueued        int
+	saturationPolicy TSaturationPolicy
+	quit             chan bool
+	// connections being answered by reject, nil while not serving
+	rejecting map[TTransport]bool
+	rejecters sync.WaitGroup
+}
+
+func NewTThreadPoolServer2(processor TProcessor, serverTransport TServerTransport) *TThreadPoolServer {
+	return NewTThreadPoolServerFactory2(NewTProcessorFactory(processor), serverTransport)
+}
+
+func NewTThreadPoolServer4(processor TProcessor, serverTransport TServerTransport, transportFactory TTransportFactory, protocolFactory TProtocolFactory) *TThreadPoolServer {
+	return NewTThreadPoolServerFactory4(NewTProcessorFactory(processor),
+		serverTransport,
+		transportFactory,
+		protocolFactory,
+	)
+}
+
+func NewTThreadPoolServer6(processor TProcessor, serverTransport TServerTransport, inputTransportFactory TTransportFactory, outputTransportFactory TTransportFactory, inputProtocolFactory TProtocolFactory, outputProtocolFactory TProtocolFactory) *TThreadPoolServer {
+	return NewTThreadPoolServerFactory6(NewTProcessorFactory(processor),
+		serverTransport,
+		inputTransportFactory,
+		outputTransportFactory,
+		inputProtocolFactory,
+		outputProtocolFactory,
+	)
+}
+
+func NewTThreadPoolServerFactory2(processorFactory TProcessorFactory, serverTransport TServerTransport) *TThreadPoolServer {
+	return NewTThreadPoolServerFactory6(processorFactory,
+		serverTransport,
+		NewTTransportFactory(),
+		NewTTransportFactory(),
+		NewTBinaryProtocolFactoryDefault(),
+		NewTBinaryProtocolFactoryDefault(),
+	)
+}
+
+func NewTThreadPoolServerFactory4(processorFactory TProcessorFactory, serverTransport TServerTransport, transportFactory TTransportFactory, protocolFactory TProtocolFactory) *TThreadPoolServer {
+	return NewTThreadPoolServerFactory6(processorFactory,
+		serverTransport,
+		transportFactory,
+		transportFactory,
+		protocolFactory,
+		protocolFactory,
+	)
+}
+
+func NewTThreadPoolServerFactory6(processorFactory TProcessorFactory, serverTransport TServerTransport, inputTransportFactory TTransportFactory, outputTransportFactory TTransportFactory, inputProtocolFactory TProtocolFactory, outputProtocolFactory TProtocolFactory) *TThreadPoolServer {
+	return &TThreadPoolServer{processorFactory: processorFactory,
+		serverTransport:        serverTransport,
+		inputTransportFactory:  inputTransportFactory,
+		outputTransportFactory: outputTransportFactory,
+		inputProtocolFactory:   inputProtocolFactory,
+		outputProtocolFactory:  outputProtocolFactory,
+		maxWorkers:             DEFAULT_THREAD_POOL_MAX_WORKERS,
+		maxQueued:              DEFAULT_THREAD_POOL_MAX_QUEUED,
+		saturationPolicy:       SATURATION_BLOCK,
+	}
+}
+
+func (p *TThreadPoolServer) ProcessorFactory() TProcessorFactory {
+	return p.processorFactory
+}
+
+func (p *TThreadPoolServer) ServerTransport() TServerTransport {
+	return p.serverTransport
+}
+
+func (p *TThreadPoolServer) InputTransportFactory() TTransportFactory {
+	return p.inputTransportFactory
+}
+
+func (p *TThreadPoolServer) OutputTransportFactory() TTransportFactory {
+	return p.outputTransportFactory
+}
+
+func (p *TThreadPoolServer) InputProtocolFactory() TProtocolFactory {
+	return p.inputProtocolFactory
+}
+
+func (p *TThreadPoolServer) OutputProtocolFactory() TProtocolFactory {
+	return p.outputProtocolFactory
+}
+
+func (p *TThreadPoolServer) ServerEventHandler() TServerEventHandler {
+	return p.eventHandler
+}
+
+/**
+ * Sets the handler notified of server and connection events.  Must be called
+ * before Serve().
+ */
+func (p *TThreadPoolServer) SetServerEventHandler(eventHandler TServerEventHandler) {
+	p.eventHandler = eventHandler
+}
+
+func (p *TThreadPoolServer) MaxWorkers() int {
+	return p.maxWorkers
+}
+
+/**
+ * Sets the maximum number of connections served at once.  Takes effect on
+ * the next call to Serve().
+ */
+func (p *TThreadPoolServer) SetMaxWorkers(maxWorkers int) {
+	if maxWorkers < 1 {
+		maxWorkers = 1
+	}
+	p.maxWorkers = maxWorkers
+}
+
+func (p *TThreadPoolServer) MaxQueued() int {
+	return p.maxQueued
+}
+
+/**
+ * Sets the number of accepted connections that may wait for a worker before
+ * the saturation policy applies.  Takes effect on the next call to Serve().
+ */
+func (p *TThreadPoolServer) SetMaxQueued(maxQueued int) {
+	if maxQueued < 0 {
+		maxQueued = 0
+	}
+	p.maxQueued = maxQueued
+}
+
+func (p *TThreadPoolServer) SaturationPolicy() TSaturationPolicy {
+	return p.saturationPolicy
+}
+
+func (p *TThreadPoolServer) SetSaturationPolicy(saturationPolicy TSaturationPolicy) {
+	p.saturationPolicy = saturationPolicy
+}
+
+func (p *TThreadPoolServer) Logger() TLogger {
+	return p.logger
+}
+
+/**
+ * Sets where panics recovered while processing are logged; LOGGER if nil.
+ */
+func (p *TThreadPoolServer) SetLogger(logger TLogger) {
+	p.logger = logger
+}
+
+func (p *TThreadPoolServer) Serve() error {
+	p.connections.start()
+	err := p.serverTransport.Listen()
+	if err != nil {
+		return err
+	}
+	if p.eventHandler != nil {
+		p.eventHandler.PreServe()
+	}
+	// a connection holds a slot from being accepted until it is closed, so
+	// there are never more than maxWorkers served plus maxQueued waiting
+	queue := make(chan *tServerConnection, p.maxWorkers+p.maxQueued)
+	slots := make(chan bool, p.maxWorkers+p.maxQueued)
+	quit := make(chan bool)
+	p.lock.Lock()
+	p.quit = quit
+	p.rejecting = make(map[TTransport]bool)
+	p.lock.Unlock()
+	for i := 0; i < p.maxWorkers; i++ {
+		go p.worker(queue, slots, quit)
+	}
+	defer p.stopWorkers()
+	// the accept loop is the only one queueing, so once it is over nothing
+	// is queued after the workers have closed what they left
+	defer p.closeQueued(queue, slots)
+	for !p.connections.isStopped() {
+		client, err := p.serverTransport.Accept()
+		if err != nil {
+			if p.connections.isStopped() {
+				return nil
+			}
+			return err
+		}
+		if client != nil {
+			p.dispatch(client, queue, slots, quit)
+		}
+	}
+	return nil
+}
+
+func (p *TThreadPoolServer) Stop() error {
+	p.connections.stop()
+	p.serverTransport.Interrupt()
+	p.stopWorkers()
+	return nil
+}
+
+/**
+ * Stops accepting connections and waits up to nsecTimeout for the
+ * connections being served to finish their current message.  Idle and
+ * queued connections are closed straight away.
+ */
+func (p *TThreadPoolServer) Shutdown(nsecTimeout int64) error {
+	p.connections.stop()
+	p.serverTransport.Interrupt()
+	err := p.connections.drain(nsecTimeout)
+	p.stopWorkers()
+	return err
+}
+
+func (p *TThreadPoolServer) IsStopped() bool {
+	return p.connections.isStopped()
+}
+
+/**
+ * Stops the workers and closes the connections waiting to be rejected,
+ * returning once their rejecters are done.
+ */
+func (p *TThreadPoolServer) stopWorkers() {
+	p.lock.Lock()
+	if p.quit != nil {
+		close(p.quit)
+		p.quit = nil
+	}
+	for client := range p.rejecting {
+		interruptTransport(client)
+	}
+	p.rejecting = nil
+	p.lock.Unlock()
+	p.rejecters.Wait()
+}
+
+func (p *TThreadPoolServer) worker(queue chan *tServerConnection, slots chan bool, quit chan bool) {
+	for {
+		select {
+		case conn := <-queue:
+			p.connections.serve(p, conn)
+			<-slots
+		case <-quit:
+			p.closeQueued(queue, slots)
+			return
+		}
+	}
+}
+
+/**
+ * Closes the connections waiting for a worker.
+ */
+func (p *TThreadPoolServer) closeQueued(queue chan *tServerConnection, slots chan bool) {
+	for {
+		select {
+		case conn := <-queue:
+			p.connections.remove(conn)
+			conn.Close()
+			<-slots
+		default:
+			return
+		}
+	}
+}
+
+func (p *TThreadPoolServer) dispatch(client TTransport, queue chan *tServerConnection, slots chan bool, quit chan bool) {
+	select {
+	case slots <- true:
+		p.enqueue(client, queue, slots)
+		return
+	default:
+	}
+	switch p.saturationPolicy {
+	case SATURATION_REJECT_CLOSE:
+		client.Close()
+	case SATURATION_REJECT_EXCEPTION:
+		// answered aside so that a client slow to send its request does not
+		// hold up the accept loop
+		if p.startReject(client) {
+			go p.reject(client)
+		} else {
+			client.Close()
+		}
+	default:
+		select {
+		case slots <- true:
+			p.enqueue(client, queue, slots)
+		case <-quit:
+			client.Close()
+		}
+	}
+}
+
+func (p *TThreadPoolServer) enqueue(client TTransport, queue chan *tServerConnection, slots chan bool) {
+	if conn := p.connections.add(client); conn != nil {
+		queue <- conn
+	} else {
+		client.Close()
+		<-slots
+	}
+}
+
+/**
+ * Registers client as being rejected, unless the server is stopped or
+ * already waiting on THREAD_POOL_MAX_REJECTING rejected clients.
+ */
+func (p *TThreadPoolServer) startReject(client TTransport) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.rejecting == nil || len(p.rejecting) >= THREAD_POOL_MAX_REJECTING {
+		return false
+	}
+	p.rejecting[client] = true
+	p.rejecters.Add(1)
+	return true
+}
+
+func (p *TThreadPoolServer) endReject(client TTransport) {
+	p.lock.Lock()
+	delete(p.rejecting, client)
+	p.lock.Unlock()
+	client.Close()
+	p.rejecters.Done()
+}
+
+/**
+ * Answers the first request on client with an INTERNAL_ERROR so that the
+ * caller sees an application error rather than a dropped connection.  A
+ * client that does not send its request in time is just closed.
+ */
+func (p *TThreadPoolServer) reject(client TTransport) {
+	defer p.endReject(client)
+	setTransportDeadline(client, time.Now().Add(THREAD_POOL_REJECT_TIMEOUT))
+	inputTransport := p.inputTransportFactory.GetTransport(client)
+	outputTransport := p.outputTransportFactory.GetTransport(client)
+	inputProtocol := p.inputProtocolFactory.GetProtocol(inputTransport)
+	outputProtocol := p.outputProtocolFactory.GetProtocol(outputTransport)
+	if headerProtocol, ok := inputProtocol.(*THeaderProtocol); ok {
+		// replies must go out in the dialect the request was read in
+		outputProtocol = headerProtocol
+	}
+	name, _, seqId, err := inputProtocol.ReadMessageBegin()
+	if err != nil {
+		return
+	}
+	inputProtocol.Skip(STRUCT)
+	inputProtocol.ReadMessageEnd()
+	x := NewTApplicationException(INTERNAL_ERROR, "Server is saturated, try again later")
+	outputProtocol.WriteMessageBegin(name, EXCEPTION, seqId)
+	x.Write(outputProtocol)
+	outputProtocol.WriteMessageEnd()
+	outputProtocol.Flush()
+}
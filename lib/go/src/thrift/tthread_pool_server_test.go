@@ -0,0 +1,227 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func listenForTest(t *testing.T) (*TServerSocket, net.Addr) {
+	addr, err := FindAvailableTCPServerPort(40000)
+	if err != nil {
+		t.Fatalf("Unable to find available tcp port addr: %s", err)
+	}
+	serverTransport, err := NewTServerSocketAddrTimeout(addr, 5e9)
+	if err != nil {
+		t.Fatalf("Unable to create server socket: %s", err)
+	}
+	if err := serverTransport.Listen(); err != nil {
+		t.Fatalf("Unable to listen on %s: %s", addr, err)
+	}
+	return serverTransport, addr
+}
+
+/**
+ * Starts a TNonblockingServer answering every call with an empty reply.
+ */
+func startTestServer(t *testing.T) (*TNonblockingServer, net.Addr) {
+	serverTransport, addr := listenForTest(t)
+	server := NewTNonblockingServer2(&recordingProcessor{}, serverTransport)
+	go server.Serve()
+	return server, addr
+}
+
+func startThreadPoolServer(t *testing.T, policy TSaturationPolicy) (*TThreadPoolServer, net.Addr) {
+	serverTransport, addr := listenForTest(t)
+	server := NewTThreadPoolServer2(&recordingProcessor{}, serverTransport)
+	server.SetMaxWorkers(1)
+	server.SetMaxQueued(0)
+	server.SetSaturationPolicy(policy)
+	go server.Serve()
+	return server, addr
+}
+
+func openTestClient(t *testing.T, addr net.Addr) (*TSocket, TProtocol) {
+	trans := NewTSocket(addr, 5e9)
+	if err := trans.Open(); err != nil {
+		t.Fatalf("Unable to open client socket: %s", err)
+	}
+	return trans, NewTBinaryProtocolTransport(trans)
+}
+
+func occupyWorker(t *testing.T, addr net.Addr) *TSocket {
+	trans, prot := openTestClient(t, addr)
+	writeEmptyCall(t, prot, "busy", 1)
+	_, typeId, _, err := prot.ReadMessageBegin()
+	if err != nil || typeId != REPLY {
+		t.Fatalf("Expected REPLY from the only worker, but found %d %v", typeId, err)
+	}
+	prot.Skip(STRUCT)
+	prot.ReadMessageEnd()
+	return trans
+}
+
+func TestThreadPoolServerRejectWithException(t *testing.T) {
+	server, addr := startThreadPoolServer(t, SATURATION_REJECT_EXCEPTION)
+	defer server.Stop()
+	busy := occupyWorker(t, addr)
+	defer busy.Close()
+
+	trans, prot := openTestClient(t, addr)
+	defer trans.Close()
+	writeEmptyCall(t, prot, "ping", 2)
+	_, typeId, seqid, err := prot.ReadMessageBegin()
+	if err != nil || typeId != EXCEPTION || seqid != 2 {
+		t.Fatalf("Expected EXCEPTION reply with seqid 2, but found %d %d %v", typeId, seqid, err)
+	}
+	x, _ := NewTApplicationExceptionDefault().Read(prot)
+	if x.TypeId() != INTERNAL_ERROR {
+		t.Fatalf("Expected INTERNAL_ERROR, but found %d", x.TypeId())
+	}
+}
+
+func TestThreadPoolServerRejectWithClose(t *testing.T) {
+	server, addr := startThreadPoolServer(t, SATURATION_REJECT_CLOSE)
+	defer server.Stop()
+	busy := occupyWorker(t, addr)
+	defer busy.Close()
+
+	trans, prot := openTestClient(t, addr)
+	defer trans.Close()
+	writeEmptyCall(t, prot, "ping", 2)
+	if _, _, _, err := prot.ReadMessageBegin(); err == nil {
+		t.Fatalf("Expected the saturated server to close the connection")
+	}
+}
+
+func TestThreadPoolServerBlockServesQueuedConnection(t *testing.T) {
+	server, addr := startThreadPoolServer(t, SATURATION_BLOCK)
+	defer server.Stop()
+	busy := occupyWorker(t, addr)
+
+	trans, prot := openTestClient(t, addr)
+	defer trans.Close()
+	writeEmptyCall(t, prot, "ping", 2)
+	busy.Close()
+	_, typeId, seqid, err := prot.ReadMessageBegin()
+	if err != nil || typeId != REPLY || seqid != 2 {
+		t.Fatalf("Expected REPLY with seqid 2 once the worker freed up, but found %d %d %v", typeId, seqid, err)
+	}
+}
+
+func TestThreadPoolServerRejectDoesNotBlockAccept(t *testing.T) {
+	server, addr := startThreadPoolServer(t, SATURATION_REJECT_EXCEPTION)
+	defer server.Stop()
+	busy := occupyWorker(t, addr)
+	defer busy.Close()
+
+	// never sends its request
+	silent, _ := openTestClient(t, addr)
+	defer silent.Close()
+	trans, prot := openTestClient(t, addr)
+	defer trans.Close()
+	trans.SetTimeout(int64(THREAD_POOL_REJECT_TIMEOUT / 2))
+	writeEmptyCall(t, prot, "ping", 2)
+	if _, typeId, _, err := prot.ReadMessageBegin(); err != nil || typeId != EXCEPTION {
+		t.Fatalf("Expected EXCEPTION while another rejected client is silent, but found %d %v", typeId, err)
+	}
+}
+
+func waitForRejecting(t *testing.T, server *TThreadPoolServer, n int) {
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		server.lock.Lock()
+		rejecting := len(server.rejecting)
+		server.lock.Unlock()
+		if rejecting == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d connections waiting to be rejected, but found %d", n, rejecting)
+		}
+	}
+}
+
+func TestThreadPoolServerRejectClosesBeyondLimit(t *testing.T) {
+	server, addr := startThreadPoolServer(t, SATURATION_REJECT_EXCEPTION)
+	defer server.Stop()
+	busy := occupyWorker(t, addr)
+	defer busy.Close()
+
+	for i := 0; i < THREAD_POOL_MAX_REJECTING; i++ {
+		silent, _ := openTestClient(t, addr)
+		defer silent.Close()
+	}
+	waitForRejecting(t, server, THREAD_POOL_MAX_REJECTING)
+	trans, prot := openTestClient(t, addr)
+	defer trans.Close()
+	trans.SetTimeout(int64(THREAD_POOL_REJECT_TIMEOUT / 2))
+	writeEmptyCall(t, prot, "ping", 2)
+	_, _, _, err := prot.ReadMessageBegin()
+	if e, ok := err.(TTransportException); err == nil || (ok && e.TypeId() == TIMED_OUT) {
+		t.Fatalf("Expected the connection beyond the rejection limit to be closed, but found %v", err)
+	}
+}
+
+func TestThreadPoolServerStopClosesRejectedConnections(t *testing.T) {
+	server, addr := startThreadPoolServer(t, SATURATION_REJECT_EXCEPTION)
+	busy := occupyWorker(t, addr)
+	defer busy.Close()
+
+	silent, prot := openTestClient(t, addr)
+	defer silent.Close()
+	waitForRejecting(t, server, 1)
+	server.Stop()
+	silent.SetTimeout(int64(THREAD_POOL_REJECT_TIMEOUT / 2))
+	_, _, _, err := prot.ReadMessageBegin()
+	if e, ok := err.(TTransportException); err == nil || (ok && e.TypeId() == TIMED_OUT) {
+		t.Fatalf("Expected the connection waiting to be rejected to be closed, but found %v", err)
+	}
+}
+
+func TestThreadPoolServerStopClosesQueuedConnections(t *testing.T) {
+	serverTransport, addr := listenForTest(t)
+	server := NewTThreadPoolServer2(&recordingProcessor{}, serverTransport)
+	server.SetMaxWorkers(1)
+	server.SetMaxQueued(1)
+	go server.Serve()
+	busy := occupyWorker(t, addr)
+	defer busy.Close()
+
+	queued, prot := openTestClient(t, addr)
+	defer queued.Close()
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		server.connections.lock.Lock()
+		accepted := len(server.connections.connections)
+		server.connections.lock.Unlock()
+		if accepted == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the second connection to be queued")
+		}
+	}
+	server.Stop()
+	_, _, _, err := prot.ReadMessageBegin()
+	if e, ok := err.(TTransportException); err == nil || (ok && e.TypeId() == TIMED_OUT) {
+		t.Fatalf("Expected the queued connection to be closed, but found %v", err)
+	}
+}
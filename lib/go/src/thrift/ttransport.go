@@ -163,6 +163,9 @@ func ReadAllTransport(p TTransport, buf []byte) (n int, err error) {
 	for n < size {
 		ret, err = p.Read(buf[n:])
 		if ret <= 0 {
+			if e, ok := err.(TTransportException); ok && e.TypeId() == TIMED_OUT {
+				return ret, err
+			}
 			if err != nil {
 				err = NewTTransportExceptionDefaultString("Cannot read. Remote side has closed. Tried to read " + strconv.Itoa(size) + " bytes, but only got " + strconv.Itoa(n) + " bytes.")
 			}
@@ -21,15 +21,18 @@ package thrift
 
 import (
 	"io"
+	"net"
 )
 
 /**
- * Transport exceptions.
- *
+ * Transport exceptions.  Err returns the error the exception was made from,
+ * if any; having it also tells a transport exception from a
+ * TProtocolException, which otherwise has the same methods.
  */
 type TTransportException interface {
 	TException
 	TypeId() int
+	Err() error
 }
 
 const (
@@ -38,11 +41,13 @@ const (
 	ALREADY_OPEN                = 2
 	TIMED_OUT                   = 3
 	END_OF_FILE                 = 4
+	CIRCUIT_BREAKER_OPEN        = 5
 )
 
 type tTransportException struct {
 	typeId  int
 	message string
+	err     error
 }
 
 func (p *tTransportException) TypeId() int {
@@ -53,6 +58,10 @@ func (p *tTransportException) Error() string {
 	return p.message
 }
 
+func (p *tTransportException) Err() error {
+	return p.err
+}
+
 func NewTTransportExceptionDefault() TTransportException {
 	return NewTTransportExceptionDefaultType(UNKNOWN_TRANSPORT_EXCEPTION)
 }
@@ -78,7 +87,10 @@ func NewTTransportExceptionFromOsError(e error) TTransportException {
 		return t
 	}
 	if e == io.EOF {
-		return NewTTransportException(END_OF_FILE, e.Error())
+		return &tTransportException{typeId: END_OF_FILE, message: e.Error(), err: e}
+	}
+	if ne, ok := e.(net.Error); ok && ne.Timeout() {
+		return &tTransportException{typeId: TIMED_OUT, message: e.Error(), err: e}
 	}
-	return NewTTransportExceptionDefaultString(e.Error())
+	return &tTransportException{typeId: UNKNOWN_TRANSPORT_EXCEPTION, message: e.Error(), err: e}
 }
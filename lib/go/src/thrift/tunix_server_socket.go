@@ -0,0 +1,163 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+/**
+ * TServerSocket listening on a Unix domain socket, handing out the accepted
+ * connections as TSockets; clients connect with NewTUnixSocket.
+ *
+ * Listening removes a socket file left behind at the path by a server that
+ * is gone, but fails if a server still answers there or the path is not a
+ * socket.  The socket file gets mode, unless it is zero, before it appears
+ * at the path, and is removed when the server socket is closed.
+ */
+type TUnixServerSocket struct {
+	*TServerSocket
+	mode os.FileMode
+}
+
+func NewTUnixServerSocket(path string, mode os.FileMode) (*TUnixServerSocket, TTransportException) {
+	return NewTUnixServerSocketTimeout(path, mode, 0)
+}
+
+func NewTUnixServerSocketTimeout(path string, mode os.FileMode, nsecClientTimeout int64) (*TUnixServerSocket, TTransportException) {
+	s, err := NewTServerSocketAddrTimeout(&net.UnixAddr{Name: path, Net: "unix"}, nsecClientTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &TUnixServerSocket{TServerSocket: s, mode: mode}, nil
+}
+
+/**
+ * Returns the path of the socket file.
+ */
+func (p *TUnixServerSocket) Path() string {
+	return p.addr.String()
+}
+
+func (p *TUnixServerSocket) Listen() error {
+	if p.listener != nil {
+		return nil
+	}
+	if err := removeStaleUnixSocket(p.Path()); err != nil {
+		return err
+	}
+	if p.mode == 0 {
+		l, err := net.Listen("unix", p.Path())
+		if err != nil {
+			return err
+		}
+		p.listener = l
+		return nil
+	}
+	l, err := listenUnixMode(p.Path(), p.mode)
+	if err != nil {
+		return err
+	}
+	p.listener = l
+	return nil
+}
+
+/**
+ * Starts listening.
+ */
+func (p *TUnixServerSocket) Open() error {
+	if p.IsOpen() {
+		return NewTTransportException(ALREADY_OPEN, "Server socket already open")
+	}
+	return p.Listen()
+}
+
+func (p *TUnixServerSocket) Accept() (TTransport, error) {
+	if p.listener == nil {
+		if err := p.Listen(); err != nil {
+			return nil, NewTTransportExceptionFromOsError(err)
+		}
+	}
+	return p.acceptListening()
+}
+
+/**
+ * Listens on a socket file at path that no one can connect to before it has
+ * mode.  The file is made in a new directory only the process can enter,
+ * given mode there and then linked to path, which fails if path exists.
+ */
+func listenUnixMode(path string, mode os.FileMode) (net.Listener, error) {
+	dir, err := ioutil.TempDir(filepath.Dir(path), ".thrift")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: filepath.Join(dir, "s"), Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	// dir is removed below; Close removes the file at path instead
+	l.SetUnlinkOnClose(false)
+	if err = os.Chmod(filepath.Join(dir, "s"), mode); err == nil {
+		err = os.Link(filepath.Join(dir, "s"), path)
+	}
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+	return &tUnixListener{UnixListener: l, path: path}, nil
+}
+
+/**
+ * Unix listener removing the socket file at path once it is closed.
+ */
+type tUnixListener struct {
+	*net.UnixListener
+	path string
+}
+
+func (p *tUnixListener) Close() error {
+	err := p.UnixListener.Close()
+	os.Remove(p.path)
+	return err
+}
+
+/**
+ * Removes the socket file at path if no server answers on it any more.
+ */
+func removeStaleUnixSocket(path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return NewTTransportException(NOT_OPEN, "Not a socket: "+path)
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return NewTTransportException(NOT_OPEN, "A server is already listening on "+path)
+	}
+	return os.Remove(path)
+}
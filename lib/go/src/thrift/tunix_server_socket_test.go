@@ -0,0 +1,114 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnixSocketCallsServer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "thrift.sock")
+	serverTransport, _ := NewTUnixServerSocketTimeout(path, 0600, 5e9)
+	if err := serverTransport.Listen(); err != nil {
+		t.Fatalf("Unable to listen on %s: %s", path, err)
+	}
+	server := NewTNonblockingServer2(&recordingProcessor{}, serverTransport)
+	go server.Serve()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Unable to stat the socket file: %s", err)
+	}
+	if info.Mode()&os.ModeSocket == 0 || info.Mode().Perm() != 0600 {
+		t.Fatalf("Expected a socket file with mode 0600, but found %s", info.Mode())
+	}
+	trans := NewTUnixSocket(path, 5e9)
+	if err := trans.Open(); err != nil {
+		t.Fatalf("Unable to open Unix socket: %s", err)
+	}
+	if err := pingCall(NewTBinaryProtocolTransport(trans)); err != nil {
+		t.Fatalf("Unable to call over a Unix socket: %s", err)
+	}
+	trans.Close()
+	server.Shutdown(5e9)
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Fatalf("Expected the socket file to be removed once the server stopped, but found %v", err)
+	}
+}
+
+func TestUnixServerSocketModeLeavesNothingBehind(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "thrift.sock")
+	serverTransport, _ := NewTUnixServerSocket(path, 0600)
+	if err := serverTransport.Listen(); err != nil {
+		t.Fatalf("Unable to listen on %s: %s", path, err)
+	}
+	if names, _ := ioutil.ReadDir(dir); len(names) != 1 || names[0].Name() != "thrift.sock" {
+		t.Fatalf("Expected only the socket file next to it, but found %v", names)
+	}
+	serverTransport.Close()
+	if names, _ := ioutil.ReadDir(dir); len(names) != 0 {
+		t.Fatalf("Expected the socket file to be removed on close, but found %v", names)
+	}
+}
+
+func TestUnixServerSocketRemovesStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "thrift.sock")
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatalf("Unable to listen on %s: %s", path, err)
+	}
+	l.SetUnlinkOnClose(false)
+	l.Close()
+
+	serverTransport, _ := NewTUnixServerSocket(path, 0)
+	if err := serverTransport.Listen(); err != nil {
+		t.Fatalf("Expected the stale socket file to be replaced, but found %s", err)
+	}
+	defer serverTransport.Close()
+
+	again, _ := NewTUnixServerSocket(path, 0)
+	if err := again.Listen(); err == nil {
+		again.Close()
+		t.Fatalf("Expected listening where a server is listening to fail")
+	}
+	if _, err := os.Lstat(path); err != nil {
+		t.Fatalf("Expected the live socket file to be kept, but found %s", err)
+	}
+}
+
+func TestUnixServerSocketKeepsOtherFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "thrift.sock")
+	if err := ioutil.WriteFile(path, []byte("data"), 0600); err != nil {
+		t.Fatalf("Unable to write %s: %s", path, err)
+	}
+	serverTransport, _ := NewTUnixServerSocket(path, 0)
+	if err := serverTransport.Listen(); err == nil {
+		serverTransport.Close()
+		t.Fatalf("Expected listening over a regular file to fail")
+	}
+	if data, err := ioutil.ReadFile(path); err != nil || string(data) != "data" {
+		t.Fatalf("Expected the regular file to be kept, but found %q %v", data, err)
+	}
+}
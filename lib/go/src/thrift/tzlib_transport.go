@@ -0,0 +1,149 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"compress/zlib"
+	"io"
+	"time"
+)
+
+/**
+ * Compresses what is written to the transport underneath and decompresses
+ * what is read from it, as one zlib stream in each direction, compatible
+ * with TZlibTransport of the C++, Java and Python libraries.  Every Flush
+ * ends with a zlib sync flush so that the other side can decompress all that
+ * was written so far.
+ *
+ * It can wrap a socket directly, or sit under or over a TFramedTransport:
+ * under it, the frames are compressed as they go; over it, each frame
+ * carries what was compressed since the last Flush.  Both sides must stack
+ * their transports the same way.
+ */
+type TZlibTransport struct {
+	transport TTransport
+	reader    io.ReadCloser
+	writer    *zlib.Writer
+}
+
+type tZlibTransportFactory struct {
+	factory TTransportFactory
+	level   int
+}
+
+/**
+ * Returns a factory wrapping the transports of factory in TZlibTransports
+ * compressing at level, from zlib.HuffmanOnly to zlib.BestCompression, or
+ * zlib.DefaultCompression.
+ */
+func NewTZlibTransportFactory(factory TTransportFactory, level int) (TTransportFactory, TTransportException) {
+	if _, err := zlib.NewWriterLevel(nil, level); err != nil {
+		return nil, NewTTransportExceptionDefaultString(err.Error())
+	}
+	return &tZlibTransportFactory{factory: factory, level: level}, nil
+}
+
+func (p *tZlibTransportFactory) GetTransport(base TTransport) TTransport {
+	t, _ := NewTZlibTransport(p.factory.GetTransport(base), p.level)
+	return t
+}
+
+/**
+ * Wraps transport, compressing at level, from zlib.HuffmanOnly to
+ * zlib.BestCompression, or zlib.DefaultCompression.
+ */
+func NewTZlibTransport(transport TTransport, level int) (*TZlibTransport, TTransportException) {
+	writer, err := zlib.NewWriterLevel(transport, level)
+	if err != nil {
+		return nil, NewTTransportExceptionDefaultString(err.Error())
+	}
+	return &TZlibTransport{transport: transport, writer: writer}, nil
+}
+
+func (p *TZlibTransport) Open() error {
+	return p.transport.Open()
+}
+
+func (p *TZlibTransport) IsOpen() bool {
+	return p.transport.IsOpen()
+}
+
+func (p *TZlibTransport) Peek() bool {
+	return p.transport.Peek()
+}
+
+/**
+ * Closes the transport underneath without ending the compressed stream, so
+ * that streams start over if it is opened again.
+ */
+func (p *TZlibTransport) Close() error {
+	if p.reader != nil {
+		p.reader.Close()
+		p.reader = nil
+	}
+	p.writer.Reset(p.transport)
+	return p.transport.Close()
+}
+
+func (p *TZlibTransport) SetDeadline(deadline time.Time) error {
+	return setTransportDeadline(p.transport, deadline)
+}
+
+func (p *TZlibTransport) Read(buf []byte) (int, error) {
+	if p.reader == nil {
+		// reading the stream header blocks, so wait until there is a read
+		reader, err := zlib.NewReader(p.transport)
+		if err != nil {
+			return 0, NewTTransportExceptionFromOsError(err)
+		}
+		p.reader = reader
+	}
+	n, err := p.reader.Read(buf)
+	return n, NewTTransportExceptionFromOsError(err)
+}
+
+func (p *TZlibTransport) ReadAll(buf []byte) (int, error) {
+	return ReadAllTransport(p, buf)
+}
+
+func (p *TZlibTransport) Write(buf []byte) (int, error) {
+	n, err := p.writer.Write(buf)
+	return n, NewTTransportExceptionFromOsError(err)
+}
+
+func (p *TZlibTransport) Flush() error {
+	if err := p.writer.Flush(); err != nil {
+		return NewTTransportExceptionFromOsError(err)
+	}
+	return NewTTransportExceptionFromOsError(p.transport.Flush())
+}
+
+/**
+ * Ends the compressed stream written so far with its checksum and flushes
+ * it, for streams that are stored rather than sent over a connection.  What
+ * is written afterwards starts a new stream.
+ */
+func (p *TZlibTransport) Finish() error {
+	if err := p.writer.Close(); err != nil {
+		return NewTTransportExceptionFromOsError(err)
+	}
+	p.writer.Reset(p.transport)
+	return NewTTransportExceptionFromOsError(p.transport.Flush())
+}
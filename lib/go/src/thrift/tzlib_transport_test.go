@@ -0,0 +1,110 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements. See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership. The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License. You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thrift
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io/ioutil"
+	"testing"
+)
+
+func newTestZlibTransport(t *testing.T, trans TTransport) *TZlibTransport {
+	zlibTrans, err := NewTZlibTransport(trans, zlib.BestCompression)
+	if err != nil {
+		t.Fatalf("Unable to create zlib transport: %s", err)
+	}
+	return zlibTrans
+}
+
+func TestZlibTransport(t *testing.T) {
+	trans := newTestZlibTransport(t, NewTMemoryBuffer())
+	TransportTest(t, trans, trans)
+}
+
+func TestZlibTransportWithFramedTransport(t *testing.T) {
+	inside := NewTFramedTransport(newTestZlibTransport(t, NewTMemoryBuffer()))
+	TransportTest(t, inside, inside)
+	outside := newTestZlibTransport(t, NewTFramedTransport(NewTMemoryBuffer()))
+	TransportTest(t, outside, outside)
+}
+
+func TestZlibTransportReadsSyncFlushedStream(t *testing.T) {
+	// written by Python's zlib.compressobj(), as its TZlibTransport does
+	stream := []byte{
+		0x78, 0x9c, 0xca, 0x48, 0xcd, 0xc9, 0xc9, 0x57, 0x48, 0x2b, 0xca, 0xcf, 0x55,
+		0x28, 0xa8, 0x2c, 0xc9, 0xc8, 0xcf, 0x03, 0x00, 0x00, 0x00, 0xff, 0xff,
+	}
+	mem := NewTMemoryBuffer()
+	mem.Write(stream)
+	buf := make([]byte, len("hello from python"))
+	if _, err := newTestZlibTransport(t, mem).ReadAll(buf); err != nil {
+		t.Fatalf("Unable to read the stream: %s", err)
+	}
+	if string(buf) != "hello from python" {
+		t.Fatalf("Expected %q, but found %q", "hello from python", buf)
+	}
+}
+
+func TestZlibTransportFinish(t *testing.T) {
+	mem := NewTMemoryBuffer()
+	trans := newTestZlibTransport(t, mem)
+	trans.Write(transport_bdata)
+	if err := trans.Finish(); err != nil {
+		t.Fatalf("Unable to finish the stream: %s", err)
+	}
+	reader, err := zlib.NewReader(bytes.NewReader(mem.Bytes()))
+	if err != nil {
+		t.Fatalf("Unable to read the stream header: %s", err)
+	}
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Unable to read the finished stream: %s", err)
+	}
+	if !bytes.Equal(data, transport_bdata) {
+		t.Fatalf("Expected the data written, but found %d other bytes", len(data))
+	}
+}
+
+func TestZlibTransportInvalidLevel(t *testing.T) {
+	if _, err := NewTZlibTransport(NewTMemoryBuffer(), 42); err == nil {
+		t.Fatalf("Expected an invalid compression level to be refused")
+	}
+	if _, err := NewTZlibTransportFactory(NewTTransportFactory(), 42); err == nil {
+		t.Fatalf("Expected an invalid compression level to be refused by the factory")
+	}
+}
+
+func TestZlibTransportCallsServer(t *testing.T) {
+	serverTransport, addr := listenForTest(t)
+	factory, _ := NewTZlibTransportFactory(NewTTransportFactory(), zlib.DefaultCompression)
+	server := NewTNonblockingServer4(&recordingProcessor{}, serverTransport, NewTFramedTransportFactory(factory), NewTBinaryProtocolFactoryDefault())
+	go server.Serve()
+	defer server.Stop()
+
+	socket, _ := openTestClient(t, addr)
+	trans := NewTFramedTransport(newTestZlibTransport(t, socket))
+	defer trans.Close()
+	for i := 0; i < 2; i++ {
+		if err := pingCall(NewTBinaryProtocolTransport(trans)); err != nil {
+			t.Fatalf("Unable to call over a zlib transport: %s", err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package simple
+
+import (
+	"net"
+	"testing"
+	"thrift"
+)
+
+type asyncEchoHandler struct{}
+
+func (p *asyncEchoHandler) Echo(message *ContainerOfEnums) (*ContainerOfEnums, error) {
+	return message, nil
+}
+
+func openAsyncEchoClient(t *testing.T) (*ContainerOfEnumsTestServiceClient, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to find a free port: %s", err)
+	}
+	addr := l.Addr()
+	l.Close()
+	serverTransport, terr := thrift.NewTServerSocketAddr(addr)
+	if terr != nil {
+		t.Fatalf("Unable to create server socket: %s", terr)
+	}
+	if err := serverTransport.Listen(); err != nil {
+		t.Fatalf("Unable to listen on %s: %s", addr, err)
+	}
+	server := thrift.NewTSimpleServer2(NewContainerOfEnumsTestServiceProcessor(&asyncEchoHandler{}), serverTransport)
+	go server.Serve()
+
+	trans := thrift.NewTSocket(addr, 5e9)
+	if err := trans.Open(); err != nil {
+		server.Stop()
+		t.Fatalf("Unable to open client socket: %s", err)
+	}
+	client := NewContainerOfEnumsTestServiceClientFactory(trans, thrift.NewTBinaryProtocolFactoryDefault())
+	return client, func() {
+		client.Close()
+		server.Stop()
+	}
+}
+
+func TestEchoAsyncFutures(t *testing.T) {
+	client, stop := openAsyncEchoClient(t)
+	defer stop()
+
+	futures := make([]*EchoFuture, 10)
+	for i := range futures {
+		message := NewContainerOfEnums()
+		message.First = UndefinedValues(i % 3)
+		futures[i] = client.EchoAsync(message)
+	}
+	// collected in reverse so that each Get finds its reply already read
+	for i := len(futures) - 1; i >= 0; i-- {
+		reply, err := futures[i].Get()
+		if err != nil {
+			t.Fatalf("Unable to get echo %d: %s", i, err)
+		}
+		if reply.First != UndefinedValues(i%3) {
+			t.Fatalf("Expected echo %d of %v, but found %v", i, UndefinedValues(i%3), reply.First)
+		}
+	}
+	if _, err := futures[0].Get(); err != nil {
+		t.Fatalf("Expected Get to be repeatable, but found %s", err)
+	}
+}
+
+func TestEchoAsyncFailsOnceClosed(t *testing.T) {
+	client, stop := openAsyncEchoClient(t)
+	defer stop()
+
+	client.Close()
+	if _, err := client.EchoAsync(NewContainerOfEnums()).Get(); err == nil {
+		t.Fatalf("Expected a call on a closed client to fail")
+	}
+}
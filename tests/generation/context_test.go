@@ -0,0 +1,62 @@
+package simple
+
+import (
+	"context"
+	"net"
+	"testing"
+	"thrift"
+	"time"
+)
+
+/**
+ * Waits in Echo until its context is done, reporting whether it was.
+ */
+type waitingEchoHandler struct {
+	started   chan bool
+	cancelled chan bool
+}
+
+func (p *waitingEchoHandler) Echo(ctx context.Context, message *ContainerOfEnums) (*ContainerOfEnums, error) {
+	p.started <- true
+	select {
+	case <-ctx.Done():
+		p.cancelled <- true
+	case <-time.After(5 * time.Second):
+		p.cancelled <- false
+	}
+	return message, nil
+}
+
+func TestHandlerContextCancelledWhenClientHangsUp(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to find a free port: %s", err)
+	}
+	addr := l.Addr()
+	l.Close()
+	serverTransport, terr := thrift.NewTServerSocketAddr(addr)
+	if terr != nil {
+		t.Fatalf("Unable to create server socket: %s", terr)
+	}
+	if err := serverTransport.Listen(); err != nil {
+		t.Fatalf("Unable to listen on %s: %s", addr, err)
+	}
+	handler := &waitingEchoHandler{started: make(chan bool, 1), cancelled: make(chan bool, 1)}
+	server := thrift.NewTSimpleServer2(NewContainerOfEnumsTestServiceProcessor(handler), serverTransport)
+	go server.Serve()
+	defer server.Stop()
+
+	trans := thrift.NewTSocket(addr, 5e9)
+	if err := trans.Open(); err != nil {
+		t.Fatalf("Unable to open client socket: %s", err)
+	}
+	client := NewContainerOfEnumsTestServiceClientFactory(trans, thrift.NewTBinaryProtocolFactoryDefault())
+	if err := client.SendEcho(NewContainerOfEnums()); err != nil {
+		t.Fatalf("Unable to send echo: %s", err)
+	}
+	<-handler.started
+	trans.Close()
+	if !<-handler.cancelled {
+		t.Fatalf("Expected the handler's context to be cancelled once the client hung up")
+	}
+}
@@ -0,0 +1,163 @@
+/* Autogenerated by Thrift Compiler (0.9.0)
+ *
+ * DO NOT EDIT UNLESS YOU ARE SURE THAT YOU KNOW WHAT YOU ARE DOING
+ */
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"simple"
+	"strconv"
+	"thrift"
+)
+
+func Usage() {
+	fmt.Fprint(os.Stderr, "Usage of ", os.Args[0], " [-h host:port] [-u url] [-unix path] [-f[ramed]] [-buffered] [-ssl] function [arg1 [arg2...]]:\n")
+	flag.PrintDefaults()
+	fmt.Fprint(os.Stderr, "Functions:\n")
+	fmt.Fprint(os.Stderr, "  echo(message *ContainerOfEnums) (retval31 *ContainerOfEnums, err error)\n")
+	fmt.Fprint(os.Stderr, "\n")
+	os.Exit(0)
+}
+
+func main() {
+	flag.Usage = Usage
+	var host string
+	var port int
+	var protocol string
+	var urlString string
+	var unixPath string
+	var framed bool
+	var buffered bool
+	var useHttp bool
+	var useSsl bool
+	var help bool
+	var parsedUrl url.URL
+	var trans thrift.TTransport
+	flag.Usage = Usage
+	flag.StringVar(&host, "h", "localhost", "Specify host and port")
+	flag.IntVar(&port, "p", 9090, "Specify port")
+	flag.StringVar(&protocol, "P", "binary", "Specify the protocol (binary, compact, simplejson, json)")
+	flag.StringVar(&urlString, "u", "", "Specify the url")
+	flag.StringVar(&unixPath, "unix", "", "Specify the path of a Unix socket")
+	flag.BoolVar(&framed, "framed", false, "Use framed transport")
+	flag.BoolVar(&buffered, "buffered", false, "Use buffered transport")
+	flag.BoolVar(&useHttp, "http", false, "Use http")
+	flag.BoolVar(&useSsl, "ssl", false, "Use TLS")
+	flag.BoolVar(&help, "help", false, "See usage string")
+	flag.Parse()
+	if help || flag.NArg() == 0 {
+		flag.Usage()
+	}
+
+	if len(urlString) > 0 {
+		parsedUrl, err := url.Parse(urlString)
+		if err != nil {
+			fmt.Fprint(os.Stderr, "Error parsing URL: ", err.Error(), "\n")
+			flag.Usage()
+		}
+		host = parsedUrl.Host
+		useHttp = len(parsedUrl.Scheme) <= 0 || parsedUrl.Scheme == "http"
+	} else if useHttp {
+		_, err := url.Parse(fmt.Sprint("http://", host, ":", port))
+		if err != nil {
+			fmt.Fprint(os.Stderr, "Error parsing URL: ", err.Error(), "\n")
+			flag.Usage()
+		}
+	}
+
+	cmd := flag.Arg(0)
+	var err error
+	if useHttp {
+		trans, err = thrift.NewTHttpClient(parsedUrl.String())
+	} else {
+		var addr net.Addr
+		if len(unixPath) > 0 {
+			addr, err = net.ResolveUnixAddr("unix", unixPath)
+		} else {
+			addr, err = net.ResolveTCPAddr("tcp", fmt.Sprint(host, ":", port))
+		}
+		if err != nil {
+			fmt.Fprint(os.Stderr, "Error resolving address", err.Error())
+			os.Exit(1)
+		}
+		if useSsl {
+			trans = thrift.NewTSSLSocketAddr(addr, &tls.Config{ServerName: host})
+		} else {
+			trans, err = thrift.NewTNonblockingSocketAddr(addr)
+		}
+		if framed {
+			trans = thrift.NewTFramedTransport(trans)
+		} else if buffered {
+			trans = thrift.NewTBufferedTransport(trans)
+		}
+	}
+	if err != nil {
+		fmt.Fprint(os.Stderr, "Error creating transport", err.Error())
+		os.Exit(1)
+	}
+	defer trans.Close()
+	var protocolFactory thrift.TProtocolFactory
+	switch protocol {
+	case "compact":
+		protocolFactory = thrift.NewTCompactProtocolFactory()
+		break
+	case "simplejson":
+		protocolFactory = thrift.NewTSimpleJSONProtocolFactory()
+		break
+	case "json":
+		protocolFactory = thrift.NewTJSONProtocolFactory()
+		break
+	case "binary", "":
+		protocolFactory = thrift.NewTBinaryProtocolFactoryDefault()
+		break
+	default:
+		fmt.Fprint(os.Stderr, "Invalid protocol specified: ", protocol, "\n")
+		Usage()
+		os.Exit(1)
+	}
+	client := simple.NewContainerOfEnumsTestServiceClientFactory(trans, protocolFactory)
+	if err = trans.Open(); err != nil {
+		fmt.Fprint(os.Stderr, "Error opening socket to ", host, ":", port, " ", err.Error())
+		os.Exit(1)
+	}
+
+	switch cmd {
+	case "echo":
+		if flag.NArg()-1 != 1 {
+			fmt.Fprint(os.Stderr, "Echo requires 1 args\n")
+			flag.Usage()
+		}
+		arg32 := flag.Arg(1)
+		mbTrans33 := thrift.NewTMemoryBufferLen(len(arg32))
+		defer mbTrans33.Close()
+		_, err34 := mbTrans33.WriteString(arg32)
+		if err34 != nil {
+			Usage()
+			return
+		}
+		factory35 := thrift.NewTSimpleJSONProtocolFactory()
+		jsProt36 := factory35.GetProtocol(mbTrans33)
+		argvalue0 := simple.NewContainerOfEnums()
+		err37 := argvalue0.Read(jsProt36)
+		if err37 != nil {
+			Usage()
+			return
+		}
+		value0 := argvalue0
+		fmt.Print(client.Echo(value0))
+		fmt.Print("\n")
+		break
+	case "":
+		Usage()
+		break
+	default:
+		fmt.Fprint(os.Stderr, "Invalid function ", cmd, "\n")
+	}
+}
@@ -34,6 +34,8 @@ type ContainerOfEnumsTestServiceClient struct {
 	InputProtocol   thrift.TProtocol
 	OutputProtocol  thrift.TProtocol
 	SeqId           int32
+	Timeouts        thrift.TClientTimeouts
+	Retries         thrift.TRetryPolicy
 }
 
 func NewContainerOfEnumsTestServiceClientFactory(t thrift.TTransport, f thrift.TProtocolFactory) *ContainerOfEnumsTestServiceClient {
@@ -59,11 +61,16 @@ func NewContainerOfEnumsTestServiceClientProtocol(t thrift.TTransport, iprot thr
  *  - Message
  */
 func (p *ContainerOfEnumsTestServiceClient) Echo(message *ContainerOfEnums) (retval19 *ContainerOfEnums, err error) {
-	err = p.SendEcho(message)
-	if err != nil {
-		return
+	retry := p.Retries.Begin("echo", p.Transport)
+	for {
+		done := thrift.WatchTimeout(p.Timeouts.Timeout("echo"), p.Transport)
+		if err = p.SendEcho(message); err == nil {
+			retval19, err = p.RecvEcho()
+		}
+		if err = done(err); !retry.Again(err) {
+			return
+		}
 	}
-	return p.RecvEcho()
 }
 
 func (p *ContainerOfEnumsTestServiceClient) SendEcho(message *ContainerOfEnums) (err error) {
@@ -180,6 +187,15 @@ func (p *containerOfEnumsTestServiceProcessorEcho) Process(seqId int32, iprot, o
 		return
 	}
 	iprot.ReadMessageEnd()
+	if x := thrift.RecordProcessorCallArgs(iprot, args); x != nil {
+		oprot.WriteMessageBegin("echo", thrift.EXCEPTION, seqId)
+		x.Write(oprot)
+		oprot.WriteMessageEnd()
+		if err = oprot.Transport().Flush(); err != nil {
+			return
+		}
+		return true, nil
+	}
 	result := NewEchoResult()
 	if result.Success, err = p.handler.Echo(args.Message); err != nil {
 		x := thrift.NewTApplicationException(thrift.INTERNAL_ERROR, "Internal error processing echo: "+err.Error())
@@ -189,6 +205,7 @@ func (p *containerOfEnumsTestServiceProcessorEcho) Process(seqId int32, iprot, o
 		oprot.Transport().Flush()
 		return
 	}
+	thrift.RecordProcessorCallResult(iprot, result)
 	if err2 := oprot.WriteMessageBegin("echo", thrift.REPLY, seqId); err2 != nil {
 		err = err2
 	}
@@ -5,6 +5,7 @@
 package main
 
 import (
+	"crypto/tls"
 	"flag"
 	"fmt"
 	"math"
@@ -17,7 +18,7 @@ import (
 )
 
 func Usage() {
-	fmt.Fprint(os.Stderr, "Usage of ", os.Args[0], " [-h host:port] [-u url] [-f[ramed]] function [arg1 [arg2...]]:\n")
+	fmt.Fprint(os.Stderr, "Usage of ", os.Args[0], " [-h host:port] [-u url] [-unix path] [-f[ramed]] [-buffered] [-ssl] function [arg1 [arg2...]]:\n")
 	flag.PrintDefaults()
 	fmt.Fprint(os.Stderr, "Functions:\n")
 	fmt.Fprint(os.Stderr, "  echo(message *ContainerOfEnums) (retval32 *ContainerOfEnums, err error)\n")
@@ -31,8 +32,11 @@ func main() {
 	var port int
 	var protocol string
 	var urlString string
+	var unixPath string
 	var framed bool
+	var buffered bool
 	var useHttp bool
+	var useSsl bool
 	var help bool
 	var parsedUrl url.URL
 	var trans thrift.TTransport
@@ -41,8 +45,11 @@ func main() {
 	flag.IntVar(&port, "p", 9090, "Specify port")
 	flag.StringVar(&protocol, "P", "binary", "Specify the protocol (binary, compact, simplejson, json)")
 	flag.StringVar(&urlString, "u", "", "Specify the url")
+	flag.StringVar(&unixPath, "unix", "", "Specify the path of a Unix socket")
 	flag.BoolVar(&framed, "framed", false, "Use framed transport")
+	flag.BoolVar(&buffered, "buffered", false, "Use buffered transport")
 	flag.BoolVar(&useHttp, "http", false, "Use http")
+	flag.BoolVar(&useSsl, "ssl", false, "Use TLS")
 	flag.BoolVar(&help, "help", false, "See usage string")
 	flag.Parse()
 	if help || flag.NArg() == 0 {
@@ -70,14 +77,25 @@ func main() {
 	if useHttp {
 		trans, err = thrift.NewTHttpClient(parsedUrl.String())
 	} else {
-		addr, err := net.ResolveTCPAddr("tcp", fmt.Sprint(host, ":", port))
+		var addr net.Addr
+		if len(unixPath) > 0 {
+			addr, err = net.ResolveUnixAddr("unix", unixPath)
+		} else {
+			addr, err = net.ResolveTCPAddr("tcp", fmt.Sprint(host, ":", port))
+		}
 		if err != nil {
 			fmt.Fprint(os.Stderr, "Error resolving address", err.Error())
 			os.Exit(1)
 		}
-		trans, err = thrift.NewTNonblockingSocketAddr(addr)
+		if useSsl {
+			trans = thrift.NewTSSLSocketAddr(addr, &tls.Config{ServerName: host})
+		} else {
+			trans, err = thrift.NewTNonblockingSocketAddr(addr)
+		}
 		if framed {
 			trans = thrift.NewTFramedTransport(trans)
+		} else if buffered {
+			trans = thrift.NewTBufferedTransport(trans)
 		}
 	}
 	if err != nil {
@@ -1,8 +1,13 @@
 package simple
 
 import (
+	"io"
+	"io/ioutil"
+	"net"
+	"sync/atomic"
 	"testing"
 	"thrift"
+	"time"
 )
 
 func TestUndefinedValuesString(t *testing.T) {
@@ -554,3 +559,159 @@ func TestWireFormatWithSetPayload(t *testing.T) {
 		}
 	}
 }
+
+type echoHandler struct{}
+
+func (p *echoHandler) Echo(message *ContainerOfEnums) (*ContainerOfEnums, error) {
+	return message, nil
+}
+
+func TestProcessorMiddlewareSeesArgsAndResult(t *testing.T) {
+	processor := NewContainerOfEnumsTestServiceProcessor(&echoHandler{})
+	var call *thrift.TProcessorCall
+	thrift.WrapProcessorFunctions(processor, func(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+		return thrift.TProcessorFunctionFunc(func(seqId int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+			ok, err := next.Process(seqId, in, out)
+			call = thrift.ProcessorCall(in)
+			return ok, err
+		})
+	})
+
+	trans := thrift.NewTMemoryBuffer()
+	client := NewContainerOfEnumsTestServiceClientFactory(trans, thrift.NewTBinaryProtocolFactoryDefault())
+	message := NewContainerOfEnums()
+	message.First = UndefinedValues_Two
+	if err := client.SendEcho(message); err != nil {
+		t.Fatalf("Unable to send echo: %s", err)
+	}
+	prot := thrift.NewTBinaryProtocolTransport(trans)
+	if ok, err := processor.Process(prot, prot); !ok || err != nil {
+		t.Fatalf("Expected successful processing, but found %v %v", ok, err)
+	}
+	if call == nil || call.Name != "echo" {
+		t.Fatalf("Expected middleware to see the echo call, but found %+v", call)
+	}
+	if args, ok := call.Args.(*EchoArgs); !ok || args.Message.First != UndefinedValues_Two {
+		t.Fatalf("Expected decoded EchoArgs, but found %#v", call.Args)
+	}
+	if result, ok := call.Result.(*EchoResult); !ok || result.Success.First != UndefinedValues_Two {
+		t.Fatalf("Expected EchoResult, but found %#v", call.Result)
+	}
+	if reply, err := client.RecvEcho(); err != nil || reply.First != UndefinedValues_Two {
+		t.Fatalf("Expected echoed message, but found %v %v", reply, err)
+	}
+}
+
+type countingEchoHandler struct {
+	calls int
+}
+
+func (p *countingEchoHandler) Echo(message *ContainerOfEnums) (*ContainerOfEnums, error) {
+	p.calls++
+	return message, nil
+}
+
+func TestProcessorMiddlewareRefusesCall(t *testing.T) {
+	handler := &countingEchoHandler{}
+	processor := NewContainerOfEnumsTestServiceProcessor(handler)
+	thrift.WrapProcessorFunctions(processor, func(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+		return thrift.TProcessorFunctionFunc(func(seqId int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+			thrift.ProcessorCall(in).OnArgs(func(call *thrift.TProcessorCall) thrift.TApplicationException {
+				if args := call.Args.(*EchoArgs); args.Message.First != UndefinedValues_Two {
+					return nil
+				}
+				return thrift.NewTApplicationException(thrift.UNKNOWN_APPLICATION_EXCEPTION, "not authorized")
+			})
+			return next.Process(seqId, in, out)
+		})
+	})
+
+	trans := thrift.NewTMemoryBuffer()
+	client := NewContainerOfEnumsTestServiceClientFactory(trans, thrift.NewTBinaryProtocolFactoryDefault())
+	message := NewContainerOfEnums()
+	message.First = UndefinedValues_Two
+	if err := client.SendEcho(message); err != nil {
+		t.Fatalf("Unable to send echo: %s", err)
+	}
+	prot := thrift.NewTBinaryProtocolTransport(trans)
+	if ok, err := processor.Process(prot, prot); !ok || err != nil {
+		t.Fatalf("Expected the refused call to leave the connection usable, but found %v %v", ok, err)
+	}
+	if handler.calls != 0 {
+		t.Fatalf("Expected the handler not to be called, but it was called %d times", handler.calls)
+	}
+	if _, err := client.RecvEcho(); err == nil || err.Error() != "not authorized" {
+		t.Fatalf("Expected the exception from the hook, but found %v", err)
+	}
+}
+
+func TestClientMethodTimeout(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer serverConn.Close()
+	go io.Copy(ioutil.Discard, serverConn)
+	trans, terr := thrift.NewTSocketConn(clientConn)
+	if terr != nil {
+		t.Fatalf("Unable to wrap client pipe: %s", terr)
+	}
+	client := NewContainerOfEnumsTestServiceClientFactory(trans, thrift.NewTBinaryProtocolFactoryDefault())
+	client.Timeouts = thrift.TClientTimeouts{Methods: map[string]time.Duration{"echo": 50 * time.Millisecond}}
+	_, err := client.Echo(NewContainerOfEnums())
+	if e, ok := err.(thrift.TTransportException); !ok || e.TypeId() != thrift.TIMED_OUT {
+		t.Fatalf("Expected TIMED_OUT, but found %v", err)
+	}
+	if trans.IsOpen() {
+		t.Fatalf("Expected the transport to be closed after a timeout")
+	}
+}
+
+type slowFirstCallHandler struct {
+	calls int32
+}
+
+func (p *slowFirstCallHandler) Echo(message *ContainerOfEnums) (*ContainerOfEnums, error) {
+	if atomic.AddInt32(&p.calls, 1) == 1 {
+		time.Sleep(200 * time.Millisecond)
+	}
+	return message, nil
+}
+
+func TestClientRetriesIdempotentMethod(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to find a free port: %s", err)
+	}
+	addr := l.Addr()
+	l.Close()
+	serverTransport, terr := thrift.NewTServerSocketAddr(addr)
+	if terr != nil {
+		t.Fatalf("Unable to create server socket: %s", terr)
+	}
+	if err := serverTransport.Listen(); err != nil {
+		t.Fatalf("Unable to listen on %s: %s", addr, err)
+	}
+	handler := &slowFirstCallHandler{}
+	server := thrift.NewTNonblockingServer2(NewContainerOfEnumsTestServiceProcessor(handler), serverTransport)
+	go server.Serve()
+	defer server.Stop()
+
+	trans := thrift.NewTSocket(addr, 5e9)
+	if err := trans.Open(); err != nil {
+		t.Fatalf("Unable to open client socket: %s", err)
+	}
+	defer trans.Close()
+	client := NewContainerOfEnumsTestServiceClientFactory(trans, thrift.NewTBinaryProtocolFactoryDefault())
+	client.Timeouts = thrift.TClientTimeouts{Default: 50 * time.Millisecond}
+	client.Retries = thrift.TRetryPolicy{Idempotent: map[string]bool{"echo": true}, InitialBackoff: time.Millisecond}
+	message := NewContainerOfEnums()
+	message.First = UndefinedValues_Two
+	reply, err := client.Echo(message)
+	if err != nil {
+		t.Fatalf("Expected the timed out call to be retried, but found %s", err)
+	}
+	if reply.First != UndefinedValues_Two {
+		t.Fatalf("Expected the message to be echoed, but found %v", reply)
+	}
+	if calls := atomic.LoadInt32(&handler.calls); calls != 2 {
+		t.Fatalf("Expected 2 calls to reach the handler, but found %d", calls)
+	}
+}